@@ -6,12 +6,16 @@ import (
 	"log"
 	"os"
 	"os/signal"
+	"strings"
 	"syscall"
 	"time"
 
 	"go-journey/src/database"
 	"go-journey/src/database/migrations"
+	"go-journey/src/mailer"
 	"go-journey/src/router"
+	"go-journey/src/service"
+	"go-journey/src/utils"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/gofiber/fiber/v2/middleware/cors"
@@ -51,12 +55,48 @@ func main() {
 	database.ConnectDB()
 	migrations.Migrate()
 
+	// Signing keys
+	if len(os.Args) > 1 && os.Args[1] == "rotate-keys" {
+		kid, err := utils.RotateSigningKey()
+		if err != nil {
+			log.Fatalf("❌ Key rotation failed: %v", err)
+		}
+		log.Printf("🔑 Rotated signing key, new kid: %s", kid)
+		return
+	}
+	if err := utils.LoadKeyring(); err != nil {
+		log.Fatalf("❌ Failed to load signing keys: %v", err)
+	}
+	utils.StartKeyringRefresher(5 * time.Minute)
+	// Any client can opt in to cookie sessions, whose CSRF tokens need a key
+	if !utils.CSRFSecretConfigured() {
+		log.Fatal("❌ CSRF_SECRET or JWT_SECRET must be set for the CSRF tokens of cookie sessions")
+	}
+
+	// Access token denylist
+	utils.InitDenylist()
+	utils.StartDenylistPruner(10 * time.Minute)
+	utils.StartTokenVersionPruner(time.Minute)
+
+	// Failed login counters
+	service.StartLoginThrottlePruner(time.Hour)
+
+	// Outgoing mail
+	mailer.Init()
+
 	// Fiber app config
 	app := fiber.New(fiber.Config{
 		AppName:       "User API v1.0",
 		Prefork:       false,
 		CaseSensitive: true,
 		StrictRouting: false,
+		// Behind a load balancer the client address comes from its header, which
+		// is only believed on connections from TRUSTED_PROXIES. Rate limits and
+		// login lockouts are per IP and would otherwise share one bucket.
+		ProxyHeader:             os.Getenv("PROXY_HEADER"),
+		EnableTrustedProxyCheck: os.Getenv("PROXY_HEADER") != "",
+		TrustedProxies:          trustedProxies(),
+		EnableIPValidation:      true,
 		ErrorHandler: func(c *fiber.Ctx, err error) error {
 			code := fiber.StatusInternalServerError
 			if e, ok := err.(*fiber.Error); ok {
@@ -75,17 +115,23 @@ func main() {
 	}))
 	app.Use(recover.New())
 
-	// CORS
+	// CORS; cookie sessions from another origin need credentials and explicit origins
 	app.Use(cors.New(cors.Config{
-		AllowOrigins: os.Getenv("CORS_ALLOW_ORIGINS"),
-		AllowMethods: "GET,POST,PUT,DELETE,OPTIONS",
-		AllowHeaders: "Origin, Content-Type, Accept, Authorization",
+		AllowOrigins:     os.Getenv("CORS_ALLOW_ORIGINS"),
+		AllowMethods:     "GET,POST,PUT,PATCH,DELETE,OPTIONS",
+		AllowHeaders:     "Origin, Content-Type, Accept, Authorization, X-Auth-Mode, X-CSRF-Token",
+		AllowCredentials: os.Getenv("CORS_ALLOW_CREDENTIALS") == "true",
 	}))
 
 	// Routes
 	router.UserRoutes(app)
 	router.AuthRoutes(app)
+	router.RoleRoutes(app)
+	router.LockoutRoutes(app)
+	router.InvitationRoutes(app)
 	router.DocsRoutes(app)
+	router.WellKnownRoutes(app)
+	router.OIDCRoutes(app)
 
 	// Port
 	port := os.Getenv("PORT")
@@ -126,3 +172,14 @@ func main() {
 
 	log.Println("✅ Server exited properly")
 }
+
+// trustedProxies parses the comma separated TRUSTED_PROXIES (IPs or CIDR ranges)
+func trustedProxies() []string {
+	var proxies []string
+	for _, proxy := range strings.Split(os.Getenv("TRUSTED_PROXIES"), ",") {
+		if proxy = strings.TrimSpace(proxy); proxy != "" {
+			proxies = append(proxies, proxy)
+		}
+	}
+	return proxies
+}
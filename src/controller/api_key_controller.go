@@ -0,0 +1,106 @@
+package controller
+
+import (
+	"go-journey/src/model"
+	"go-journey/src/res"
+	"go-journey/src/service"
+	"go-journey/src/utils"
+	"go-journey/src/validation"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// @Summary      List API keys
+// @Description  List the current user's active API keys. Secrets are never returned.
+// @Tags         Auth
+// @Produce      json
+// @Security     Bearer
+// @Success      200 {object} res.Response{data=[]model.APIKey}
+// @Failure      401 {object} res.Response
+// @Failure      500 {object} res.Response
+// @Router       /auth/api-keys [get]
+func GetAPIKeys(c *fiber.Ctx) error {
+	keys, err := service.GetAPIKeys(c.Locals("userID").(string))
+	if err != nil {
+		return utils.InternalError(c, err)
+	}
+	return c.JSON(res.SuccessResponse("API keys fetched successfully", keys))
+}
+
+// @Summary      Create API key
+// @Description  Create a personal access token for scripts and CI. The key is only shown in this response. Scopes are permission names; without scopes the key has all permissions of the user's role.
+// @Tags         Auth
+// @Accept       json
+// @Produce      json
+// @Security     Bearer
+// @Param        payload  body      validation.CreateAPIKeyRequest  true  "API key data"
+// @Success      201 {object} res.Response{data=map[string]interface{}}
+// @Failure      400 {object} res.Response
+// @Failure      500 {object} res.Response
+// @Router       /auth/api-keys [post]
+func CreateAPIKey(c *fiber.Ctx) error {
+	var req validation.CreateAPIKeyRequest
+	if err := c.BodyParser(&req); err != nil {
+		return utils.ValidationError(c, err)
+	}
+	if err := validation.ValidateStruct(&req); err != nil {
+		return utils.ValidationError(c, err)
+	}
+
+	if _, err := service.GetPermissionsByName(req.Scopes); err != nil {
+		return permissionLookupError(c, err)
+	}
+	role, _ := c.Locals("role").(string)
+	for _, scope := range req.Scopes {
+		allowed, err := service.RoleHasPermission(role, scope)
+		if err != nil {
+			return utils.InternalError(c, err)
+		}
+		if !allowed {
+			return c.Status(fiber.StatusBadRequest).JSON(res.ErrorResponse("Your role does not grant "+scope, nil))
+		}
+	}
+
+	key := model.APIKey{
+		UserID: c.Locals("userID").(string),
+		Name:   strings.TrimSpace(req.Name),
+		Scopes: req.Scopes,
+	}
+	if req.ExpiresInDays > 0 {
+		expiresAt := time.Now().AddDate(0, 0, req.ExpiresInDays)
+		key.ExpiresAt = &expiresAt
+	}
+
+	secret, err := service.CreateAPIKey(&key)
+	if err != nil {
+		return utils.InternalError(c, err)
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(res.SuccessResponse("API key created, copy it now as it will not be shown again", fiber.Map{
+		"key":     secret,
+		"api_key": key,
+	}))
+}
+
+// @Summary      Revoke API key
+// @Description  Revoke one of the current user's API keys
+// @Tags         Auth
+// @Produce      json
+// @Security     Bearer
+// @Param        id   path      string  true  "API key UUID"
+// @Success      200 {object} res.Response
+// @Failure      404 {object} res.Response
+// @Failure      500 {object} res.Response
+// @Router       /auth/api-keys/{id} [delete]
+func RevokeAPIKey(c *fiber.Ctx) error {
+	found, err := service.RevokeAPIKey(c.Locals("userID").(string), c.Params("id"))
+	if err != nil {
+		return utils.InternalError(c, err)
+	}
+	if !found {
+		return c.Status(fiber.StatusNotFound).JSON(res.ErrorResponse("API key not found", nil))
+	}
+	return c.JSON(res.SuccessResponse("API key revoked successfully", nil))
+}
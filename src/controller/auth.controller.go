@@ -1,26 +1,34 @@
 package controller
 
 import (
+	"errors"
+	"fmt"
 	"go-journey/src/database"
 	"go-journey/src/model"
+	"go-journey/src/password"
 	"go-journey/src/res"
+	"go-journey/src/service"
 	"go-journey/src/utils"
 	"go-journey/src/validation"
+	"log"
+	"math"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/gofiber/fiber/v2"
-	"golang.org/x/crypto/bcrypt"
 )
 
 // ===================== REGISTER =====================
 // @Summary Register a new user
-// @Description Register a new user with role 'user' or 'admin'
+// @Description Register a new user. Depending on REGISTRATION_MODE an invite_code is required (invite) or the account stays pending until an admin activates it (approval). Other roles than 'user' can only come from an invitation.
 // @Tags Auth
 // @Accept json
 // @Produce json
 // @Param payload body validation.RegisterRequest true "Register payload"
 // @Success 201 {object} res.Response{data=map[string]interface{}}
 // @Failure 400 {object} res.Response
+// @Failure 403 {object} res.Response
 // @Failure 500 {object} res.Response
 // @Router /auth/register [post]
 func Register(c *fiber.Ctx) error {
@@ -35,12 +43,10 @@ func Register(c *fiber.Ctx) error {
 	// sanitize input
 	req.Username = strings.TrimSpace(req.Username)
 	req.FullName = strings.TrimSpace(req.FullName)
+	req.Email = service.NormalizeEmail(req.Email)
 
-	// default role
-	if req.Role == "" {
-		req.Role = "user"
-	}
-	if req.Role != "user" && req.Role != "admin" {
+	// Privileged roles are only granted by admins, through invitations
+	if req.Role != "" && req.Role != model.RoleUser {
 		return c.Status(fiber.StatusBadRequest).JSON(res.ErrorResponse("Invalid role provided", nil))
 	}
 
@@ -49,40 +55,61 @@ func Register(c *fiber.Ctx) error {
 	if count > 0 {
 		return c.Status(fiber.StatusBadRequest).JSON(res.ErrorResponse("Username already used", nil))
 	}
-
-	hash, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
-	if err != nil {
-		return utils.InternalError(c, err)
+	if service.EmailTaken(req.Email, "") {
+		return c.Status(fiber.StatusBadRequest).JSON(res.ErrorResponse("Email already used", nil))
 	}
 
 	user := model.User{
 		Username: req.Username,
+		Email:    req.Email,
 		FullName: req.FullName,
-		Password: string(hash),
-		Role:     req.Role,
+	}
+	if err := service.CheckNewPassword(&user, req.Password); err != nil {
+		return passwordPolicyError(c, err)
 	}
 
-	if err := database.DB.Create(&user).Error; err != nil {
+	hash, err := password.Hash(req.Password)
+	if err != nil {
 		return utils.InternalError(c, err)
 	}
+	user.Password = hash
 
-	tokens, err := utils.GenerateTokenPair(user.ID)
-	if err != nil {
+	err = service.RegisterUser(&user, strings.TrimSpace(req.InviteCode))
+	switch {
+	case errors.Is(err, service.ErrInvitationRequired):
+		return c.Status(fiber.StatusForbidden).JSON(res.ErrorCodeResponse("invitation_required", "Registration is by invitation only"))
+	case errors.Is(err, service.ErrInvalidInvitation):
+		return c.Status(fiber.StatusBadRequest).JSON(res.ErrorCodeResponse("invalid_invitation", "Invalid or expired invitation"))
+	case err != nil:
+		return utils.InternalError(c, err)
+	}
+	if err := service.RecordPasswordHistory(user.ID, user.Password); err != nil {
 		return utils.InternalError(c, err)
 	}
 
-	utils.SaveRefreshToken(user.ID, tokens.RefreshToken)
+	if err := service.SendEmailVerification(&user); err != nil {
+		log.Println("[Register] failed to send verification email:", err)
+	}
+
+	if user.Status == model.UserStatusPending {
+		return c.Status(fiber.StatusCreated).JSON(res.SuccessResponse("User registered successfully, an administrator must approve the account", fiber.Map{
+			"user": userPayload(&user),
+		}))
+	}
+
+	if service.EmailVerificationRequired() && user.EmailVerifiedAt == nil {
+		return c.Status(fiber.StatusCreated).JSON(res.SuccessResponse("User registered successfully, please verify your email", fiber.Map{
+			"user": userPayload(&user),
+		}))
+	}
+
+	tokens, _, err := service.StartSession(&user, sessionMeta(c, req.DeviceName))
+	if err != nil {
+		return utils.InternalError(c, err)
+	}
 
 	return c.Status(fiber.StatusCreated).JSON(res.SuccessResponse("User registered successfully", fiber.Map{
-		"user": fiber.Map{
-			"id":              user.ID,
-			"username":        user.Username,
-			"full_name":       user.FullName,
-			"role":            user.Role,
-			"register_date":   user.RegisterDate,
-			"esign_id":        user.EsignID,
-			"esign_status_id": user.EsignStatusID,
-		},
+		"user": userPayload(&user),
 		"tokens": fiber.Map{
 			"accessToken":  tokens.AccessToken,
 			"refreshToken": tokens.RefreshToken,
@@ -92,14 +119,16 @@ func Register(c *fiber.Ctx) error {
 
 // ===================== LOGIN =====================
 // @Summary Login user
-// @Description Login with username and password, returns access & refresh tokens
+// @Description Login with username and password, returns access & refresh tokens. When two-factor authentication is enabled an mfa_token is returned instead, to be exchanged at /auth/login/mfa. With "X-Auth-Mode: cookie" the tokens are set as HttpOnly cookies and only a csrfToken is returned.
 // @Tags Auth
 // @Accept json
 // @Produce json
+// @Param X-Auth-Mode header string false "Set to cookie for a browser session" Enums(cookie)
 // @Param payload body validation.LoginRequest true "Login payload"
 // @Success 200 {object} res.Response{data=map[string]interface{}}
 // @Failure 400 {object} res.Response
 // @Failure 401 {object} res.Response
+// @Failure 429 {object} res.Response
 // @Failure 500 {object} res.Response
 // @Router /auth/login [post]
 func Login(c *fiber.Ctx) error {
@@ -111,54 +140,54 @@ func Login(c *fiber.Ctx) error {
 		return utils.ValidationError(c, err)
 	}
 
+	if err := service.CheckIPLockout(c.IP()); err != nil {
+		return loginLockedError(c, err)
+	}
+
 	var user model.User
 	if err := database.DB.Where("username = ?", req.Username).First(&user).Error; err != nil {
-		return c.Status(fiber.StatusUnauthorized).JSON(res.ErrorResponse("Invalid credentials", nil))
+		return invalidCredentials(c, nil)
 	}
 
-	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(req.Password)); err != nil {
-		return c.Status(fiber.StatusUnauthorized).JSON(res.ErrorResponse("Invalid credentials", nil))
+	if err := service.CheckAccountLockout(&user, c.IP()); err != nil {
+		return loginLockedError(c, err)
 	}
 
-	tokens, err := utils.GenerateTokenPair(user.ID)
-	if err != nil {
-		return utils.InternalError(c, err)
+	ok, needsRehash, err := password.Verify(req.Password, user.Password)
+	if err != nil || !ok {
+		return invalidCredentials(c, &user)
 	}
 
-	utils.SaveRefreshToken(user.ID, tokens.RefreshToken)
+	// Upgrade hashes made with an older algorithm or parameters while we know the password
+	if needsRehash {
+		if err := service.RehashPassword(&user, req.Password); err != nil {
+			log.Println("[Login] failed to rehash password:", err)
+		}
+	}
 
-	return c.JSON(res.SuccessResponse("Login successful", fiber.Map{
-		"user": fiber.Map{
-			"id":              user.ID,
-			"username":        user.Username,
-			"full_name":       user.FullName,
-			"role":            user.Role,
-			"register_date":   user.RegisterDate,
-			"esign_id":        user.EsignID,
-			"esign_status_id": user.EsignStatusID,
-		},
-		"tokens": fiber.Map{
-			"accessToken":  tokens.AccessToken,
-			"refreshToken": tokens.RefreshToken,
-		},
-	}))
+	return completeLogin(c, &user, req.DeviceName, false)
 }
 
 // ===================== REFRESH TOKEN =====================
 // @Summary Refresh access token
-// @Description Use refresh token to get a new access token
+// @Description Use refresh token to get a new access token. With "X-Auth-Mode: cookie" the refresh token is read from its cookie, the request must carry the X-CSRF-Token header and the new tokens are set as cookies.
 // @Tags Auth
 // @Accept json
 // @Produce json
-// @Param payload body validation.RefreshRequest true "Refresh token payload"
+// @Param X-Auth-Mode header string false "Set to cookie for a browser session" Enums(cookie)
+// @Param X-CSRF-Token header string false "CSRF token, required in cookie mode"
+// @Param payload body validation.RefreshRequest false "Refresh token payload, not used in cookie mode"
 // @Success 200 {object} res.Response{data=map[string]string}
 // @Failure 400 {object} res.Response
 // @Failure 401 {object} res.Response
+// @Failure 403 {object} res.Response
 // @Failure 500 {object} res.Response
 // @Router /auth/refresh [post]
 func Refresh(c *fiber.Ctx) error {
 	var body validation.RefreshRequest
-	if err := c.BodyParser(&body); err != nil {
+	if utils.CookieMode(c) {
+		body.RefreshToken = c.Cookies(utils.RefreshTokenCookie)
+	} else if err := c.BodyParser(&body); err != nil {
 		return utils.ValidationError(c, err)
 	}
 	if err := validation.ValidateStruct(body); err != nil {
@@ -179,16 +208,46 @@ func Refresh(c *fiber.Ctx) error {
 		return c.Status(fiber.StatusUnauthorized).JSON(res.ErrorResponse("Invalid subject", nil))
 	}
 
-	if !utils.IsRefreshTokenValid(sub, body.RefreshToken) {
+	sid, ok := claims["sid"].(string)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(res.ErrorResponse("Invalid session", nil))
+	}
+	if utils.CookieMode(c) && !utils.ValidCSRF(c, sid) {
+		return c.Status(fiber.StatusForbidden).JSON(res.ErrorCodeResponse("csrf_invalid", "Invalid CSRF token"))
+	}
+
+	// Sessions of OpenID Connect clients are refreshed at /oauth2/token
+	session, err := service.GetSession(sid)
+	if err != nil || session.ClientID != "" {
+		return c.Status(fiber.StatusUnauthorized).JSON(res.ErrorResponse("Invalid session", nil))
+	}
+
+	switch utils.CheckRefreshToken(sid, body.RefreshToken) {
+	case utils.RefreshTokenReused:
+		return refreshTokenReused(c, sub, sid)
+	case utils.RefreshTokenInvalid:
+		return c.Status(fiber.StatusUnauthorized).JSON(res.ErrorResponse("Refresh token revoked", nil))
+	}
+
+	user, err := service.GetUserByID(sub)
+	if err != nil || user.Status != model.UserStatusActive {
 		return c.Status(fiber.StatusUnauthorized).JSON(res.ErrorResponse("Refresh token revoked", nil))
 	}
 
-	tokens, err := utils.GenerateTokenPair(sub)
+	tokens, err := service.RotateSession(&user, sid, body.RefreshToken)
+	if errors.Is(err, service.ErrRefreshTokenReused) {
+		return refreshTokenReused(c, sub, sid)
+	}
 	if err != nil {
 		return utils.InternalError(c, err)
 	}
 
-	utils.SaveRefreshToken(sub, tokens.RefreshToken)
+	if utils.CookieMode(c) {
+		csrf := utils.SetAuthCookies(c, tokens, sid)
+		return c.JSON(res.SuccessResponse("Token refreshed successfully", fiber.Map{
+			"csrfToken": csrf,
+		}))
+	}
 
 	return c.JSON(res.SuccessResponse("Token refreshed successfully", fiber.Map{
 		"accessToken":  tokens.AccessToken,
@@ -198,14 +257,211 @@ func Refresh(c *fiber.Ctx) error {
 
 // ===================== LOGOUT =====================
 // @Summary Logout user
-// @Description Logout user (invalidate refresh token)
+// @Description Logout the current device (invalidate its session)
 // @Tags Auth
 // @Produce json
 // @Security Bearer
 // @Success 200 {object} res.Response
 // @Router /auth/logout [post]
 func Logout(c *fiber.Ctx) error {
-	userID := c.Locals("userID").(string)
-	utils.RevokeRefreshToken(userID)
+	sessionID, _ := c.Locals("sessionID").(string)
+	jti, _ := c.Locals("jti").(string)
+	expiresAt, _ := c.Locals("tokenExpiresAt").(time.Time)
+
+	if err := utils.DenyToken(jti, expiresAt); err != nil {
+		return utils.InternalError(c, err)
+	}
+	if err := service.EndSession(sessionID); err != nil {
+		return utils.InternalError(c, err)
+	}
+	clearCookieSession(c)
 	return c.JSON(res.SuccessResponse("Logout successful", fiber.Map{}))
 }
+
+// ===================== REAUTHENTICATE =====================
+// @Summary Confirm credentials for a sensitive operation
+// @Description Confirm the password, or a TOTP or recovery code when 2FA is enabled, to get a short-lived access token that passes the recent-login check of sensitive endpoints. In cookie mode the access token cookie is replaced instead.
+// @Tags Auth
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Param payload body validation.ReauthenticateRequest true "Password or code"
+// @Success 200 {object} res.Response{data=map[string]interface{}}
+// @Failure 400 {object} res.Response
+// @Failure 401 {object} res.Response
+// @Failure 429 {object} res.Response
+// @Failure 500 {object} res.Response
+// @Router /auth/reauthenticate [post]
+func Reauthenticate(c *fiber.Ctx) error {
+	var req validation.ReauthenticateRequest
+	if err := c.BodyParser(&req); err != nil {
+		return utils.ValidationError(c, err)
+	}
+	if err := validation.ValidateStruct(req); err != nil {
+		return utils.ValidationError(c, err)
+	}
+
+	user, err := service.GetUserByID(c.Locals("userID").(string))
+	if err != nil {
+		return utils.InternalError(c, err)
+	}
+
+	sessionID, _ := c.Locals("sessionID").(string)
+	token, expiresAt, err := service.Reauthenticate(&user, sessionID, req.Password, req.Code, sessionMeta(c, ""))
+	var locked *service.LoginLockedError
+	switch {
+	case errors.Is(err, service.ErrWrongPassword):
+		return c.Status(fiber.StatusUnauthorized).JSON(res.ErrorResponse("Invalid credentials", nil))
+	case errors.As(err, &locked):
+		return loginLockedError(c, err)
+	case err != nil:
+		return mfaError(c, err)
+	}
+
+	if utils.CookieMode(c) {
+		utils.SetAccessTokenCookie(c, token, expiresAt)
+		return c.JSON(res.SuccessResponse("Reauthenticated successfully", fiber.Map{
+			"expiresAt": expiresAt,
+		}))
+	}
+	return c.JSON(res.SuccessResponse("Reauthenticated successfully", fiber.Map{
+		"accessToken": token,
+		"expiresAt":   expiresAt,
+	}))
+}
+
+// completeLogin applies the account checks shared by every login method and
+// starts a new session for the user. Users with two-factor authentication get
+// an mfa_pending token instead, unless the second factor was already verified.
+func completeLogin(c *fiber.Ctx, user *model.User, deviceName string, mfaVerified bool) error {
+	if user.Status == model.UserStatusPending {
+		return c.Status(fiber.StatusForbidden).
+			JSON(res.ErrorCodeResponse("account_pending", "Your account is awaiting approval by an administrator"))
+	}
+	if user.Status != model.UserStatusActive {
+		return c.Status(fiber.StatusForbidden).JSON(res.ErrorResponse("Account is disabled", nil))
+	}
+
+	if service.EmailVerificationRequired() && user.EmailVerifiedAt == nil {
+		return c.Status(fiber.StatusForbidden).
+			JSON(res.ErrorCodeResponse("email_not_verified", "Please verify your email before logging in"))
+	}
+
+	if !mfaVerified && service.MFAEnabled(user) {
+		mfaToken, err := utils.GenerateMFAToken(user)
+		if err != nil {
+			return utils.InternalError(c, err)
+		}
+		return c.JSON(res.SuccessResponse("Two-factor authentication required", fiber.Map{
+			"mfa_required": true,
+			"mfa_token":    mfaToken,
+		}))
+	}
+
+	if err := service.RecordLoginSuccess(user, c.IP()); err != nil {
+		return utils.InternalError(c, err)
+	}
+
+	tokens, session, err := service.StartSession(user, sessionMeta(c, deviceName))
+	if err != nil {
+		return utils.InternalError(c, err)
+	}
+
+	// Browser clients keep the tokens in HttpOnly cookies, out of reach of scripts
+	if utils.CookieMode(c) {
+		csrf := utils.SetAuthCookies(c, tokens, session.ID)
+		return c.JSON(res.SuccessResponse("Login successful", fiber.Map{
+			"user":      userPayload(user),
+			"csrfToken": csrf,
+		}))
+	}
+
+	return c.JSON(res.SuccessResponse("Login successful", fiber.Map{
+		"user": userPayload(user),
+		"tokens": fiber.Map{
+			"accessToken":  tokens.AccessToken,
+			"refreshToken": tokens.RefreshToken,
+		},
+	}))
+}
+
+// invalidCredentials counts a failed login against the client IP and, when
+// the username exists, the account from that IP.
+func invalidCredentials(c *fiber.Ctx, user *model.User) error {
+	if err := service.RecordLoginFailure(user, c.IP()); err != nil {
+		return utils.InternalError(c, err)
+	}
+	return c.Status(fiber.StatusUnauthorized).JSON(res.ErrorResponse("Invalid credentials", nil))
+}
+
+// loginLockedError answers a login attempt against a locked account or IP
+func loginLockedError(c *fiber.Ctx, err error) error {
+	var locked *service.LoginLockedError
+	if !errors.As(err, &locked) {
+		return utils.InternalError(c, err)
+	}
+	seconds := int(math.Ceil(locked.RetryAfter.Seconds()))
+	c.Set(fiber.HeaderRetryAfter, strconv.Itoa(seconds))
+	return c.Status(fiber.StatusTooManyRequests).JSON(res.ErrorCodeResponse("login_locked",
+		fmt.Sprintf("Too many failed login attempts. Please try again in %d seconds", seconds)))
+}
+
+// userPayload is the user representation returned by the auth endpoints
+func userPayload(user *model.User) fiber.Map {
+	return fiber.Map{
+		"id":                 user.ID,
+		"username":           user.Username,
+		"email":              user.Email,
+		"email_verified_at":  user.EmailVerifiedAt,
+		"mfa_enabled":        service.MFAEnabled(user),
+		"magic_link_enabled": !user.MagicLinkDisabled,
+		"full_name":          user.FullName,
+		"role":               user.Role,
+		"register_date":      user.RegisterDate,
+		"esign_id":           user.EsignID,
+		"esign_status_id":    user.EsignStatusID,
+	}
+}
+
+// refreshTokenReused revokes the token family of a replayed refresh token
+func refreshTokenReused(c *fiber.Ctx, userID string, sessionID string) error {
+	if err := service.HandleRefreshTokenReuse(userID, sessionID, sessionMeta(c, "")); err != nil {
+		return utils.InternalError(c, err)
+	}
+	clearCookieSession(c)
+	return c.Status(fiber.StatusUnauthorized).
+		JSON(res.ErrorCodeResponse("refresh_token_reused", "Refresh token reuse detected, session revoked"))
+}
+
+// clearCookieSession removes the auth cookies of a browser session that ended
+func clearCookieSession(c *fiber.Ctx) {
+	if c.Cookies(utils.AccessTokenCookie) != "" || c.Cookies(utils.RefreshTokenCookie) != "" {
+		utils.ClearAuthCookies(c)
+	}
+}
+
+// sessionMeta collects the device information stored with a new session
+func sessionMeta(c *fiber.Ctx, deviceName string) service.SessionMeta {
+	deviceName = strings.TrimSpace(deviceName)
+	if deviceName == "" {
+		deviceName = "Unknown device"
+	}
+	return service.SessionMeta{
+		DeviceName: truncate(deviceName, 100),
+		UserAgent:  truncate(c.Get(fiber.HeaderUserAgent), 255),
+		IP:         c.IP(),
+	}
+}
+
+// truncate shortens s to at most max characters without splitting a
+// multi-byte character, matching how varchar limits are counted
+func truncate(s string, max int) string {
+	if len(s) <= max {
+		return s
+	}
+	runes := []rune(s)
+	if len(runes) > max {
+		return string(runes[:max])
+	}
+	return s
+}
@@ -0,0 +1,106 @@
+package controller
+
+import (
+	"go-journey/src/model"
+	"go-journey/src/res"
+	"go-journey/src/service"
+	"go-journey/src/utils"
+	"go-journey/src/validation"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// @Summary      List invitations
+// @Description  List registration invitations, newest first
+// @Tags         invitations
+// @Produce      json
+// @Security Bearer
+// @Success      200 {object} res.Response{data=[]model.Invitation}
+// @Failure      500 {object} res.Response
+// @Router       /invitations [get]
+func GetInvitations(c *fiber.Ctx) error {
+	invitations, err := service.GetInvitations()
+	if err != nil {
+		return utils.InternalError(c, err)
+	}
+	return c.JSON(res.SuccessResponse("Invitations fetched successfully", invitations))
+}
+
+// @Summary      Create invitation
+// @Description  Invite someone to register with a preset role. With an email the invitation is sent to and only valid for that address. The code is only shown in this response. The invited role may not grant permissions the inviter lacks.
+// @Tags         invitations
+// @Accept       json
+// @Produce      json
+// @Security Bearer
+// @Param        invitation  body      validation.CreateInvitationRequest  true  "Invitation data"
+// @Success      201 {object} res.Response{data=map[string]interface{}}
+// @Failure      400 {object} res.Response
+// @Failure      403 {object} res.Response
+// @Failure      500 {object} res.Response
+// @Router       /invitations [post]
+func CreateInvitation(c *fiber.Ctx) error {
+	var req validation.CreateInvitationRequest
+	if err := c.BodyParser(&req); err != nil {
+		return utils.ValidationError(c, err)
+	}
+	if err := validation.ValidateStruct(&req); err != nil {
+		return utils.ValidationError(c, err)
+	}
+	if !service.RoleExists(req.Role) {
+		return c.Status(fiber.StatusBadRequest).JSON(res.ErrorResponse("Invalid role provided", nil))
+	}
+	// Inviting someone must not hand out more rights than the inviter has
+	allowed, err := canAssignRole(c, req.Role)
+	if err != nil {
+		return utils.InternalError(c, err)
+	}
+	if !allowed {
+		return c.Status(fiber.StatusForbidden).JSON(res.ErrorResponse("Your role does not grant every permission of "+req.Role, nil))
+	}
+	if req.Email != "" && service.EmailTaken(req.Email, "") {
+		return c.Status(fiber.StatusBadRequest).JSON(res.ErrorResponse("Email already used", nil))
+	}
+
+	ttl := utils.TTLFromEnv("INVITATION_TTL", 7*24*time.Hour)
+	if req.ExpiresInHours > 0 {
+		ttl = time.Duration(req.ExpiresInHours) * time.Hour
+	}
+
+	invitation := model.Invitation{
+		Email:     req.Email,
+		Role:      req.Role,
+		CreatedBy: c.Locals("userID").(string),
+		ExpiresAt: time.Now().Add(ttl),
+	}
+	code, err := service.CreateInvitation(&invitation)
+	if err != nil {
+		return utils.InternalError(c, err)
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(res.SuccessResponse("Invitation created successfully", fiber.Map{
+		"code":       code,
+		"invitation": invitation,
+	}))
+}
+
+// @Summary      Revoke invitation
+// @Description  Delete an invitation that has not been used yet
+// @Tags         invitations
+// @Produce      json
+// @Security Bearer
+// @Param        id   path      string  true  "Invitation UUID"
+// @Success      200 {object} res.Response
+// @Failure      404 {object} res.Response
+// @Failure      500 {object} res.Response
+// @Router       /invitations/{id} [delete]
+func RevokeInvitation(c *fiber.Ctx) error {
+	found, err := service.RevokeInvitation(c.Params("id"))
+	if err != nil {
+		return utils.InternalError(c, err)
+	}
+	if !found {
+		return c.Status(fiber.StatusNotFound).JSON(res.ErrorResponse("Invitation not found or already used", nil))
+	}
+	return c.JSON(res.SuccessResponse("Invitation revoked successfully", nil))
+}
@@ -0,0 +1,18 @@
+package controller
+
+import (
+	"go-journey/src/utils"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// @Summary      JSON Web Key Set
+// @Description  Public keys used to verify access tokens signed with RS256 or EdDSA
+// @Tags         Auth
+// @Produce      json
+// @Success      200 {object} map[string]interface{}
+// @Router       /.well-known/jwks.json [get]
+func JWKS(c *fiber.Ctx) error {
+	c.Set(fiber.HeaderCacheControl, "public, max-age=300")
+	return c.JSON(utils.JWKS())
+}
@@ -0,0 +1,75 @@
+package controller
+
+import (
+	"go-journey/src/res"
+	"go-journey/src/service"
+	"go-journey/src/utils"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// @Summary      Unlock user
+// @Description  Clear the failed login counters and lockouts of an account for every IP address
+// @Tags         users
+// @Produce      json
+// @Security Bearer
+// @Param        id   path      string  true  "User UUID"
+// @Success      200 {object} res.Response
+// @Failure      404 {object} res.Response
+// @Failure      500 {object} res.Response
+// @Router       /users/{id}/unlock [post]
+func UnlockUser(c *fiber.Ctx) error {
+	id := c.Params("id")
+
+	_, err := service.GetUserByID(id)
+	if err != nil {
+		if err.Error() == "record not found" {
+			return c.Status(fiber.StatusNotFound).
+				JSON(res.ErrorResponse("User not found", nil))
+		}
+		return utils.InternalError(c, err)
+	}
+
+	if err := service.UnlockUser(id); err != nil {
+		return utils.InternalError(c, err)
+	}
+
+	return c.JSON(res.SuccessResponse("User unlocked successfully", nil))
+}
+
+// @Summary      List locked IP addresses
+// @Description  List the IP addresses currently locked after too many failed logins
+// @Tags         lockouts
+// @Produce      json
+// @Security Bearer
+// @Success      200 {object} res.Response{data=[]model.LoginThrottle}
+// @Failure      500 {object} res.Response
+// @Router       /lockouts/ips [get]
+func GetLockedIPs(c *fiber.Ctx) error {
+	throttles, err := service.GetLockedIPs()
+	if err != nil {
+		return utils.InternalError(c, err)
+	}
+	return c.JSON(res.SuccessResponse("Locked IP addresses fetched successfully", throttles))
+}
+
+// @Summary      Unlock IP address
+// @Description  Forget the failed login attempts of an IP address
+// @Tags         lockouts
+// @Produce      json
+// @Security Bearer
+// @Param        ip   path      string  true  "IP address"
+// @Success      200 {object} res.Response
+// @Failure      404 {object} res.Response
+// @Failure      500 {object} res.Response
+// @Router       /lockouts/ips/{ip} [delete]
+func UnlockIP(c *fiber.Ctx) error {
+	found, err := service.UnlockIP(c.Params("ip"))
+	if err != nil {
+		return utils.InternalError(c, err)
+	}
+	if !found {
+		return c.Status(fiber.StatusNotFound).JSON(res.ErrorResponse("No failed logins recorded for this IP address", nil))
+	}
+	return c.JSON(res.SuccessResponse("IP address unlocked successfully", nil))
+}
@@ -0,0 +1,108 @@
+package controller
+
+import (
+	"errors"
+	"go-journey/src/res"
+	"go-journey/src/service"
+	"go-journey/src/utils"
+	"go-journey/src/validation"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// ===================== MAGIC LINK =====================
+// @Summary Request a magic link
+// @Description Email a single-use login link to a verified address. The returned device_token must be kept by the requesting client and sent with the link to /auth/magic-link/verify; the response is the same whether or not the address is registered.
+// @Tags Auth
+// @Accept json
+// @Produce json
+// @Param payload body validation.MagicLinkRequest true "Email address"
+// @Success 200 {object} res.Response{data=map[string]string}
+// @Failure 400 {object} res.Response
+// @Failure 429 {object} res.Response
+// @Failure 500 {object} res.Response
+// @Router /auth/magic-link [post]
+func RequestMagicLink(c *fiber.Ctx) error {
+	var req validation.MagicLinkRequest
+	if err := c.BodyParser(&req); err != nil {
+		return utils.ValidationError(c, err)
+	}
+	if err := validation.ValidateStruct(req); err != nil {
+		return utils.ValidationError(c, err)
+	}
+
+	deviceToken, err := service.RequestMagicLink(req.Email)
+	if err != nil {
+		return utils.InternalError(c, err)
+	}
+
+	return c.JSON(res.SuccessResponse("If the address belongs to an account, a login link has been sent", fiber.Map{
+		"device_token": deviceToken,
+	}))
+}
+
+// @Summary Log in with a magic link
+// @Description Exchange the token from the emailed link, together with the device_token returned when it was requested, for an access & refresh token. Two-factor authentication still applies.
+// @Tags Auth
+// @Accept json
+// @Produce json
+// @Param X-Auth-Mode header string false "Set to cookie for a browser session" Enums(cookie)
+// @Param payload body validation.VerifyMagicLinkRequest true "Link and device tokens"
+// @Success 200 {object} res.Response{data=map[string]interface{}}
+// @Failure 400 {object} res.Response
+// @Failure 403 {object} res.Response
+// @Failure 500 {object} res.Response
+// @Router /auth/magic-link/verify [post]
+func VerifyMagicLink(c *fiber.Ctx) error {
+	var req validation.VerifyMagicLinkRequest
+	if err := c.BodyParser(&req); err != nil {
+		return utils.ValidationError(c, err)
+	}
+	if err := validation.ValidateStruct(req); err != nil {
+		return utils.ValidationError(c, err)
+	}
+
+	user, err := service.VerifyMagicLink(req.Token, req.DeviceToken)
+	switch {
+	case errors.Is(err, service.ErrMagicLinkDevice):
+		return c.Status(fiber.StatusBadRequest).JSON(res.ErrorCodeResponse("magic_link_device_mismatch",
+			"Open the link in the browser where you requested it"))
+	case errors.Is(err, service.ErrInvalidUserToken):
+		return c.Status(fiber.StatusBadRequest).JSON(res.ErrorResponse("Invalid or expired login link", nil))
+	case err != nil:
+		return utils.InternalError(c, err)
+	}
+
+	return completeLogin(c, user, req.DeviceName, false)
+}
+
+// @Summary Enable or disable magic links
+// @Description Turn passwordless login by email on or off for the current user. Disabling it invalidates links already sent.
+// @Tags Auth
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Param payload body validation.MagicLinkSettingsRequest true "Setting"
+// @Success 200 {object} res.Response
+// @Failure 400 {object} res.Response
+// @Failure 500 {object} res.Response
+// @Router /auth/magic-link/settings [put]
+func UpdateMagicLinkSettings(c *fiber.Ctx) error {
+	var req validation.MagicLinkSettingsRequest
+	if err := c.BodyParser(&req); err != nil {
+		return utils.ValidationError(c, err)
+	}
+	if err := validation.ValidateStruct(req); err != nil {
+		return utils.ValidationError(c, err)
+	}
+
+	if err := service.SetMagicLinkEnabled(c.Locals("userID").(string), *req.Enabled); err != nil {
+		return utils.InternalError(c, err)
+	}
+
+	message := "Magic link login disabled"
+	if *req.Enabled {
+		message = "Magic link login enabled"
+	}
+	return c.JSON(res.SuccessResponse(message, nil))
+}
@@ -0,0 +1,150 @@
+package controller
+
+import (
+	"errors"
+	"go-journey/src/res"
+	"go-journey/src/service"
+	"go-journey/src/utils"
+	"go-journey/src/validation"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// @Summary      Get my profile
+// @Description  Get the account of the authenticated user
+// @Tags         users
+// @Produce      json
+// @Security Bearer
+// @Success      200 {object} res.Response{data=res.UserResponse}
+// @Failure      401 {object} res.Response
+// @Failure      500 {object} res.Response
+// @Router       /users/me [get]
+func GetMe(c *fiber.Ctx) error {
+	user, err := service.GetUserByID(c.Locals("userID").(string))
+	if err != nil {
+		return utils.InternalError(c, err)
+	}
+	return c.JSON(res.SuccessResponse("User fetched successfully", res.NewUserResponse(&user)))
+}
+
+// @Summary      Update my profile
+// @Description  Change the username, email or full name of the authenticated user. A new email must be verified again, requires a login within the last 5 minutes and is reported to the previous address. Role and e-sign fields can only be changed by an administrator.
+// @Tags         users
+// @Accept       json
+// @Produce      json
+// @Security Bearer
+// @Param        user  body      validation.UpdateProfileRequest  true  "Profile data"
+// @Success      200 {object} res.Response{data=res.UserResponse}
+// @Failure      400 {object} res.Response
+// @Failure      403 {object} res.Response
+// @Failure      500 {object} res.Response
+// @Router       /users/me [patch]
+func UpdateMe(c *fiber.Ctx) error {
+	var req validation.UpdateProfileRequest
+	if err := c.BodyParser(&req); err != nil {
+		return utils.ValidationError(c, err)
+	}
+	if err := validation.ValidateStruct(&req); err != nil {
+		return utils.ValidationError(c, err)
+	}
+
+	user, err := service.GetUserByID(c.Locals("userID").(string))
+	if err != nil {
+		return utils.InternalError(c, err)
+	}
+
+	// A new email can reset the password, so it needs a recent login like the
+	// other sensitive operations
+	if req.Email != "" && service.NormalizeEmail(req.Email) != user.Email && !utils.RecentlyAuthenticated(c, 5*time.Minute) {
+		return c.Status(fiber.StatusForbidden).JSON(res.ErrorCodeResponse("reauthentication_required",
+			"Please confirm your password to change your email"))
+	}
+
+	emailChanged, err := service.UpdateProfile(&user, service.ProfileUpdate{
+		Username: strings.TrimSpace(req.Username),
+		Email:    req.Email,
+		FullName: strings.TrimSpace(req.FullName),
+	}, sessionMeta(c, ""))
+	switch {
+	case errors.Is(err, service.ErrUsernameTaken):
+		return c.Status(fiber.StatusBadRequest).JSON(res.ErrorResponse("Username already used", nil))
+	case errors.Is(err, service.ErrEmailTaken):
+		return c.Status(fiber.StatusBadRequest).JSON(res.ErrorResponse("Email already used", nil))
+	case err != nil:
+		return utils.InternalError(c, err)
+	}
+
+	if emailChanged {
+		if err := service.SendEmailVerification(&user); err != nil {
+			log.Println("[UpdateMe] failed to send verification email:", err)
+		}
+	}
+
+	return c.JSON(res.SuccessResponse("Profile updated successfully", res.NewUserResponse(&user)))
+}
+
+// @Summary      Change my password
+// @Description  Set a new password. Requires the current password and logs out every other device.
+// @Tags         users
+// @Accept       json
+// @Produce      json
+// @Security Bearer
+// @Param        payload  body      validation.ChangePasswordRequest  true  "Current and new password"
+// @Success      200 {object} res.Response
+// @Failure      400 {object} res.Response
+// @Failure      429 {object} res.Response
+// @Failure      500 {object} res.Response
+// @Router       /users/me/password [post]
+func ChangeMyPassword(c *fiber.Ctx) error {
+	var req validation.ChangePasswordRequest
+	if err := c.BodyParser(&req); err != nil {
+		return utils.ValidationError(c, err)
+	}
+	if err := validation.ValidateStruct(&req); err != nil {
+		return utils.ValidationError(c, err)
+	}
+
+	user, err := service.GetUserByID(c.Locals("userID").(string))
+	if err != nil {
+		return utils.InternalError(c, err)
+	}
+
+	sessionID, _ := c.Locals("sessionID").(string)
+	err = service.ChangePassword(&user, req.CurrentPassword, req.NewPassword, sessionID, sessionMeta(c, ""))
+	var locked *service.LoginLockedError
+	switch {
+	case errors.Is(err, service.ErrWrongPassword):
+		return c.Status(fiber.StatusBadRequest).JSON(res.ErrorCodeResponse("invalid_password", "Current password is incorrect"))
+	case errors.As(err, &locked):
+		return loginLockedError(c, err)
+	case err != nil:
+		return passwordPolicyError(c, err)
+	}
+
+	return c.JSON(res.SuccessResponse("Password changed, other devices have been logged out", nil))
+}
+
+// @Summary      Delete my account
+// @Description  Delete the account of the authenticated user and end all of its sessions
+// @Tags         users
+// @Produce      json
+// @Security Bearer
+// @Success      200 {object} res.Response
+// @Failure      500 {object} res.Response
+// @Router       /users/me [delete]
+func DeleteMe(c *fiber.Ctx) error {
+	user, err := service.GetUserByID(c.Locals("userID").(string))
+	if err != nil {
+		return utils.InternalError(c, err)
+	}
+
+	if err := service.DeleteAccount(&user, sessionMeta(c, "")); err != nil {
+		return utils.InternalError(c, err)
+	}
+	clearCookieSession(c)
+
+	return c.JSON(res.SuccessResponse("Account deleted successfully", nil))
+}
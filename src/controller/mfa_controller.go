@@ -0,0 +1,237 @@
+package controller
+
+import (
+	"errors"
+	"go-journey/src/password"
+	"go-journey/src/res"
+	"go-journey/src/service"
+	"go-journey/src/utils"
+	"go-journey/src/validation"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// ===================== LOGIN MFA =====================
+// @Summary Complete a two-factor login
+// @Description Exchange the mfa_token from /auth/login and a TOTP or recovery code for access & refresh tokens
+// @Tags Auth
+// @Accept json
+// @Produce json
+// @Param payload body validation.LoginMFARequest true "MFA login payload"
+// @Success 200 {object} res.Response{data=map[string]interface{}}
+// @Failure 400 {object} res.Response
+// @Failure 401 {object} res.Response
+// @Failure 429 {object} res.Response
+// @Failure 500 {object} res.Response
+// @Router /auth/login/mfa [post]
+func LoginMFA(c *fiber.Ctx) error {
+	var req validation.LoginMFARequest
+	if err := c.BodyParser(&req); err != nil {
+		return utils.ValidationError(c, err)
+	}
+	if err := validation.ValidateStruct(req); err != nil {
+		return utils.ValidationError(c, err)
+	}
+
+	t, claims, err := utils.ParseToken(req.MFAToken)
+	if err != nil || !t.Valid {
+		return c.Status(fiber.StatusUnauthorized).JSON(res.ErrorResponse("Invalid or expired MFA token", nil))
+	}
+	if typ, _ := claims["type"].(string); typ != "mfa_pending" {
+		return c.Status(fiber.StatusUnauthorized).JSON(res.ErrorResponse("Invalid token type", nil))
+	}
+
+	sub, _ := claims["sub"].(string)
+	jti, _ := claims["jti"].(string)
+	if denied, err := utils.Denylist.Contains(jti); jti == "" || err != nil || denied {
+		return c.Status(fiber.StatusUnauthorized).JSON(res.ErrorResponse("Invalid or expired MFA token", nil))
+	}
+
+	user, err := service.GetUserByID(sub)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(res.ErrorResponse("Invalid or expired MFA token", nil))
+	}
+
+	if err := service.CheckIPLockout(c.IP()); err != nil {
+		return loginLockedError(c, err)
+	}
+	if err := service.CheckAccountLockout(&user, c.IP()); err != nil {
+		return loginLockedError(c, err)
+	}
+	if err := service.VerifyMFACode(&user, req.Code, sessionMeta(c, req.DeviceName)); err != nil {
+		if errors.Is(err, service.ErrInvalidMFACode) {
+			if err := service.RecordLoginFailure(&user, c.IP()); err != nil {
+				return utils.InternalError(c, err)
+			}
+		}
+		return mfaError(c, err)
+	}
+
+	// The pending token is single-use once the second factor succeeded
+	exp, _ := claims.GetExpirationTime()
+	if exp != nil {
+		if err := utils.DenyToken(jti, exp.Time); err != nil {
+			return utils.InternalError(c, err)
+		}
+	}
+
+	return completeLogin(c, &user, req.DeviceName, true)
+}
+
+// ===================== TOTP SETUP =====================
+// @Summary Start TOTP enrollment
+// @Description Generate a new authenticator secret. 2FA is only enabled after /auth/mfa/totp/confirm.
+// @Tags Auth
+// @Produce json
+// @Security Bearer
+// @Success 200 {object} res.Response{data=map[string]string}
+// @Failure 400 {object} res.Response
+// @Failure 500 {object} res.Response
+// @Router /auth/mfa/totp/setup [post]
+func SetupTOTP(c *fiber.Ctx) error {
+	user, err := service.GetUserByID(c.Locals("userID").(string))
+	if err != nil {
+		return utils.InternalError(c, err)
+	}
+
+	secret, uri, err := service.BeginTOTPEnrollment(&user)
+	if err != nil {
+		return mfaError(c, err)
+	}
+
+	return c.JSON(res.SuccessResponse("Scan the QR code with your authenticator app, then confirm with a code", fiber.Map{
+		"secret":      secret,
+		"otpauth_uri": uri,
+	}))
+}
+
+// ===================== TOTP CONFIRM =====================
+// @Summary Confirm TOTP enrollment
+// @Description Enable 2FA with a code from the authenticator. Returns one-time recovery codes that are not shown again.
+// @Tags Auth
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Param payload body validation.TOTPCodeRequest true "TOTP code"
+// @Success 200 {object} res.Response{data=map[string]interface{}}
+// @Failure 400 {object} res.Response
+// @Failure 500 {object} res.Response
+// @Router /auth/mfa/totp/confirm [post]
+func ConfirmTOTP(c *fiber.Ctx) error {
+	var req validation.TOTPCodeRequest
+	if err := c.BodyParser(&req); err != nil {
+		return utils.ValidationError(c, err)
+	}
+	if err := validation.ValidateStruct(req); err != nil {
+		return utils.ValidationError(c, err)
+	}
+
+	user, err := service.GetUserByID(c.Locals("userID").(string))
+	if err != nil {
+		return utils.InternalError(c, err)
+	}
+
+	codes, err := service.ConfirmTOTPEnrollment(&user, req.Code, sessionMeta(c, ""))
+	if err != nil {
+		return mfaError(c, err)
+	}
+
+	return c.JSON(res.SuccessResponse("Two-factor authentication enabled", fiber.Map{
+		"recovery_codes": codes,
+	}))
+}
+
+// ===================== TOTP DISABLE =====================
+// @Summary Disable TOTP
+// @Description Turn off 2FA. Requires the password and a TOTP or recovery code.
+// @Tags Auth
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Param payload body validation.DisableTOTPRequest true "Disable payload"
+// @Success 200 {object} res.Response
+// @Failure 400 {object} res.Response
+// @Failure 401 {object} res.Response
+// @Failure 500 {object} res.Response
+// @Router /auth/mfa/totp/disable [post]
+func DisableTOTP(c *fiber.Ctx) error {
+	var req validation.DisableTOTPRequest
+	if err := c.BodyParser(&req); err != nil {
+		return utils.ValidationError(c, err)
+	}
+	if err := validation.ValidateStruct(req); err != nil {
+		return utils.ValidationError(c, err)
+	}
+
+	user, err := service.GetUserByID(c.Locals("userID").(string))
+	if err != nil {
+		return utils.InternalError(c, err)
+	}
+
+	if !password.Matches(req.Password, user.Password) {
+		return c.Status(fiber.StatusUnauthorized).JSON(res.ErrorResponse("Invalid credentials", nil))
+	}
+
+	meta := sessionMeta(c, "")
+	if err := service.VerifyMFACode(&user, req.Code, meta); err != nil {
+		return mfaError(c, err)
+	}
+	if err := service.DisableTOTP(&user, meta); err != nil {
+		return mfaError(c, err)
+	}
+
+	return c.JSON(res.SuccessResponse("Two-factor authentication disabled", nil))
+}
+
+// ===================== RECOVERY CODES =====================
+// @Summary Regenerate recovery codes
+// @Description Replace all recovery codes. Requires a current TOTP code.
+// @Tags Auth
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Param payload body validation.TOTPCodeRequest true "TOTP code"
+// @Success 200 {object} res.Response{data=map[string]interface{}}
+// @Failure 400 {object} res.Response
+// @Failure 500 {object} res.Response
+// @Router /auth/mfa/recovery-codes [post]
+func RegenerateRecoveryCodes(c *fiber.Ctx) error {
+	var req validation.TOTPCodeRequest
+	if err := c.BodyParser(&req); err != nil {
+		return utils.ValidationError(c, err)
+	}
+	if err := validation.ValidateStruct(req); err != nil {
+		return utils.ValidationError(c, err)
+	}
+
+	user, err := service.GetUserByID(c.Locals("userID").(string))
+	if err != nil {
+		return utils.InternalError(c, err)
+	}
+
+	if err := service.VerifyMFACode(&user, req.Code, sessionMeta(c, "")); err != nil {
+		return mfaError(c, err)
+	}
+	codes, err := service.RegenerateRecoveryCodes(&user)
+	if err != nil {
+		return mfaError(c, err)
+	}
+
+	return c.JSON(res.SuccessResponse("Recovery codes regenerated", fiber.Map{
+		"recovery_codes": codes,
+	}))
+}
+
+func mfaError(c *fiber.Ctx, err error) error {
+	switch {
+	case errors.Is(err, service.ErrInvalidMFACode):
+		return c.Status(fiber.StatusUnauthorized).JSON(res.ErrorCodeResponse("invalid_mfa_code", "Invalid authentication code"))
+	case errors.Is(err, service.ErrMFAAlreadyEnabled):
+		return c.Status(fiber.StatusBadRequest).JSON(res.ErrorResponse("Two-factor authentication is already enabled", nil))
+	case errors.Is(err, service.ErrMFANotEnabled):
+		return c.Status(fiber.StatusBadRequest).JSON(res.ErrorResponse("Two-factor authentication is not enabled", nil))
+	case errors.Is(err, service.ErrMFAEnrollmentMissing):
+		return c.Status(fiber.StatusBadRequest).JSON(res.ErrorResponse("Start the enrollment first", nil))
+	}
+	return utils.InternalError(c, err)
+}
@@ -0,0 +1,86 @@
+package controller
+
+import (
+	"errors"
+	"go-journey/src/oauth"
+	"go-journey/src/res"
+	"go-journey/src/service"
+	"go-journey/src/utils"
+	"log"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// ===================== OAUTH LOGIN =====================
+// @Summary Start social login
+// @Description Redirect to the provider (google or github) using PKCE and a single-use state, which is also stored in an HttpOnly cookie so only this browser can complete the login
+// @Tags Auth
+// @Param provider path string true "Provider name" Enums(google, github)
+// @Success 302
+// @Failure 404 {object} res.Response
+// @Failure 500 {object} res.Response
+// @Router /auth/oauth/{provider}/login [get]
+func OAuthLogin(c *fiber.Ctx) error {
+	provider, ok := oauth.Get(c.Params("provider"))
+	if !ok {
+		return c.Status(fiber.StatusNotFound).JSON(res.ErrorResponse("Unknown or unconfigured provider", nil))
+	}
+
+	redirectURL, state, err := service.BeginOAuthLogin(provider)
+	if err != nil {
+		return utils.InternalError(c, err)
+	}
+	utils.SetOAuthStateCookie(c, state, time.Now().Add(service.OAuthStateTTL))
+
+	return c.Redirect(redirectURL, fiber.StatusFound)
+}
+
+// ===================== OAUTH CALLBACK =====================
+// @Summary Social login callback
+// @Description Validate the state against the login cookie, exchange the code and log in the linked or newly created user
+// @Tags Auth
+// @Produce json
+// @Param provider path string true "Provider name" Enums(google, github)
+// @Param code query string true "Authorization code"
+// @Param state query string true "State"
+// @Success 200 {object} res.Response{data=map[string]interface{}}
+// @Failure 400 {object} res.Response
+// @Failure 401 {object} res.Response
+// @Failure 403 {object} res.Response
+// @Failure 404 {object} res.Response
+// @Failure 409 {object} res.Response
+// @Router /auth/oauth/{provider}/callback [get]
+func OAuthCallback(c *fiber.Ctx) error {
+	provider, ok := oauth.Get(c.Params("provider"))
+	if !ok {
+		return c.Status(fiber.StatusNotFound).JSON(res.ErrorResponse("Unknown or unconfigured provider", nil))
+	}
+
+	if errCode := c.Query("error"); errCode != "" {
+		return c.Status(fiber.StatusUnauthorized).JSON(res.ErrorResponse("Authorization denied by provider", errors.New(errCode)))
+	}
+	code, state := c.Query("code"), c.Query("state")
+	if code == "" || state == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(res.ErrorResponse("Missing code or state", nil))
+	}
+	if !utils.OAuthStateMatches(c, state) {
+		return c.Status(fiber.StatusBadRequest).JSON(res.ErrorResponse("Invalid or expired login state", nil))
+	}
+
+	user, err := service.CompleteOAuthLogin(c.UserContext(), provider, code, state)
+	switch {
+	case errors.Is(err, service.ErrOAuthState):
+		return c.Status(fiber.StatusBadRequest).JSON(res.ErrorResponse("Invalid or expired login state", nil))
+	case errors.Is(err, service.ErrInvitationRequired):
+		return c.Status(fiber.StatusForbidden).JSON(res.ErrorCodeResponse("invitation_required", "Registration is by invitation only"))
+	case errors.Is(err, service.ErrOAuthEmailConflict):
+		return c.Status(fiber.StatusConflict).JSON(res.ErrorResponse(
+			"An account with this email already exists but is not verified. Verify it or log in with your password first", nil))
+	case err != nil:
+		log.Printf("[OAuth] %s login failed: %v", provider.Name, err)
+		return c.Status(fiber.StatusUnauthorized).JSON(res.ErrorResponse("Social login failed", nil))
+	}
+
+	return completeLogin(c, user, provider.Name, false)
+}
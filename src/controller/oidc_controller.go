@@ -0,0 +1,404 @@
+package controller
+
+import (
+	"encoding/base64"
+	"errors"
+	"go-journey/src/model"
+	"go-journey/src/res"
+	"go-journey/src/service"
+	"go-journey/src/utils"
+	"go-journey/src/validation"
+	"net/url"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// ===================== DISCOVERY =====================
+// @Summary      OpenID Connect discovery
+// @Description  Provider metadata for OpenID Connect clients
+// @Tags         OIDC
+// @Produce      json
+// @Success      200 {object} map[string]interface{}
+// @Router       /.well-known/openid-configuration [get]
+func OpenIDConfiguration(c *fiber.Ctx) error {
+	issuer := service.OIDCIssuer()
+	c.Set(fiber.HeaderCacheControl, "public, max-age=300")
+	return c.JSON(fiber.Map{
+		"issuer":                                        issuer,
+		"authorization_endpoint":                        issuer + "/oauth2/authorize",
+		"token_endpoint":                                issuer + "/oauth2/token",
+		"userinfo_endpoint":                             issuer + "/oauth2/userinfo",
+		"introspection_endpoint":                        issuer + "/oauth2/introspect",
+		"revocation_endpoint":                           issuer + "/oauth2/revoke",
+		"jwks_uri":                                      issuer + "/.well-known/jwks.json",
+		"response_types_supported":                      []string{"code"},
+		"grant_types_supported":                         []string{"authorization_code", "refresh_token"},
+		"subject_types_supported":                       []string{"public"},
+		"id_token_signing_alg_values_supported":         []string{utils.JWTAlgorithm()},
+		"scopes_supported":                              service.OIDCScopes,
+		"token_endpoint_auth_methods_supported":         []string{"client_secret_basic", "client_secret_post", "none"},
+		"introspection_endpoint_auth_methods_supported": []string{"client_secret_basic", "client_secret_post"},
+		"revocation_endpoint_auth_methods_supported":    []string{"client_secret_basic", "client_secret_post", "none"},
+		"code_challenge_methods_supported":              []string{"S256"},
+		"claims_supported": []string{
+			"sub", "iss", "aud", "exp", "iat", "nonce", "at_hash",
+			"name", "preferred_username", "updated_at", "email", "email_verified",
+		},
+	})
+}
+
+// ===================== AUTHORIZE =====================
+// @Summary      Start an authorization request
+// @Description  Validate the request and redirect the browser to the login page (OIDC_LOGIN_URL) with the same query string. After signing in, the login page completes the request with POST /oauth2/authorize.
+// @Tags         OIDC
+// @Param        response_type          query  string  true   "Must be code"
+// @Param        client_id              query  string  true   "Client ID"
+// @Param        redirect_uri           query  string  true   "Registered redirect URI"
+// @Param        scope                  query  string  true   "Space separated, must include openid"
+// @Param        state                  query  string  false  "Opaque client state"
+// @Param        nonce                  query  string  false  "Nonce echoed in the ID token"
+// @Param        code_challenge         query  string  true   "PKCE challenge"
+// @Param        code_challenge_method  query  string  true   "Must be S256"
+// @Success      302
+// @Failure      400 {object} res.Response
+// @Router       /oauth2/authorize [get]
+func Authorize(c *fiber.Ctx) error {
+	var req validation.AuthorizeRequest
+	if err := c.QueryParser(&req); err != nil {
+		return utils.ValidationError(c, err)
+	}
+
+	authReq := authorizationRequest(req)
+	if _, err := service.ValidateAuthorizationRequest(authReq); err != nil {
+		return authorizationError(c, authReq, err, true)
+	}
+
+	loginURL := service.OIDCLoginURL()
+	sep := "?"
+	if strings.Contains(loginURL, "?") {
+		sep = "&"
+	}
+	return c.Redirect(loginURL+sep+string(c.Request().URI().QueryString()), fiber.StatusFound)
+}
+
+// @Summary      Approve an authorization request
+// @Description  Called by the login page for the signed-in user. Returns the client redirect URL carrying the authorization code.
+// @Tags         OIDC
+// @Accept       json
+// @Produce      json
+// @Security     Bearer
+// @Param        payload  body  validation.AuthorizeRequest  true  "The parameters received by GET /oauth2/authorize"
+// @Success      200 {object} res.Response{data=map[string]string}
+// @Failure      400 {object} res.Response
+// @Failure      401 {object} res.Response
+// @Router       /oauth2/authorize [post]
+func ApproveAuthorization(c *fiber.Ctx) error {
+	var req validation.AuthorizeRequest
+	if err := c.BodyParser(&req); err != nil {
+		return utils.ValidationError(c, err)
+	}
+
+	authReq := authorizationRequest(req)
+	if _, err := service.ValidateAuthorizationRequest(authReq); err != nil {
+		return authorizationError(c, authReq, err, false)
+	}
+
+	user, err := service.GetUserByID(c.Locals("userID").(string))
+	if err != nil {
+		return utils.InternalError(c, err)
+	}
+	if user.Status != model.UserStatusActive {
+		return c.Status(fiber.StatusForbidden).JSON(res.ErrorResponse("Account is disabled", nil))
+	}
+
+	code, err := service.IssueAuthorizationCode(&user, authReq)
+	if err != nil {
+		return utils.InternalError(c, err)
+	}
+
+	return c.JSON(res.SuccessResponse("Authorization granted", fiber.Map{
+		"redirect_to": service.AuthorizationRedirect(authReq.RedirectURI, url.Values{"code": {code}}, authReq.State),
+	}))
+}
+
+// ===================== TOKEN =====================
+// @Summary      Token endpoint
+// @Description  Exchange an authorization code (with its PKCE verifier) or a refresh token for tokens. Confidential clients authenticate with HTTP Basic or client_secret in the body.
+// @Tags         OIDC
+// @Accept       x-www-form-urlencoded
+// @Produce      json
+// @Param        grant_type     formData  string  true   "authorization_code or refresh_token"
+// @Param        code           formData  string  false  "Authorization code"
+// @Param        redirect_uri   formData  string  false  "Redirect URI used in the authorization request"
+// @Param        code_verifier  formData  string  false  "PKCE verifier"
+// @Param        refresh_token  formData  string  false  "Refresh token"
+// @Param        client_id      formData  string  false  "Client ID"
+// @Param        client_secret  formData  string  false  "Client secret"
+// @Success      200 {object} service.OIDCTokens
+// @Failure      400 {object} map[string]string
+// @Failure      401 {object} map[string]string
+// @Router       /oauth2/token [post]
+func Token(c *fiber.Ctx) error {
+	c.Set(fiber.HeaderCacheControl, "no-store")
+	c.Set(fiber.HeaderPragma, "no-cache")
+
+	var req validation.TokenRequest
+	if err := c.BodyParser(&req); err != nil {
+		return oauthErrorResponse(c, &service.OAuthError{Code: "invalid_request", Description: "malformed request body"})
+	}
+
+	clientID, secret := clientCredentials(c, req.ClientID, req.ClientSecret)
+	client, err := service.AuthenticateOAuthClient(clientID, secret)
+	if err != nil {
+		return oauthErrorResponse(c, err)
+	}
+
+	meta := sessionMeta(c, client.Name)
+	var tokens service.OIDCTokens
+	switch req.GrantType {
+	case "authorization_code":
+		tokens, err = service.RedeemAuthorizationCode(client, req.Code, req.RedirectURI, req.CodeVerifier, meta)
+	case "refresh_token":
+		tokens, err = service.RefreshOIDCTokens(client, req.RefreshToken, meta)
+	default:
+		err = &service.OAuthError{Code: "unsupported_grant_type", Description: "grant_type must be authorization_code or refresh_token"}
+	}
+	if err != nil {
+		return oauthErrorResponse(c, err)
+	}
+	return c.JSON(tokens)
+}
+
+// ===================== INTROSPECTION =====================
+// @Summary      Token introspection
+// @Description  RFC 7662. Reports whether an access or refresh token is still active, taking logouts, rotation and revoked sessions into account. Requires a confidential client.
+// @Tags         OIDC
+// @Accept       x-www-form-urlencoded
+// @Produce      json
+// @Param        token            formData  string  true   "Access or refresh token"
+// @Param        token_type_hint  formData  string  false  "access_token or refresh_token"
+// @Param        client_id        formData  string  false  "Client ID"
+// @Param        client_secret    formData  string  false  "Client secret"
+// @Success      200 {object} service.Introspection
+// @Failure      400 {object} map[string]string
+// @Failure      401 {object} map[string]string
+// @Router       /oauth2/introspect [post]
+func Introspect(c *fiber.Ctx) error {
+	c.Set(fiber.HeaderCacheControl, "no-store")
+
+	var req validation.IntrospectRequest
+	if err := c.BodyParser(&req); err != nil || req.Token == "" {
+		return oauthErrorResponse(c, &service.OAuthError{Code: "invalid_request", Description: "token is required"})
+	}
+
+	client, err := service.AuthenticateOAuthClient(clientCredentials(c, req.ClientID, req.ClientSecret))
+	if err != nil {
+		return oauthErrorResponse(c, err)
+	}
+
+	result, err := service.IntrospectToken(client, req.Token)
+	if err != nil {
+		return oauthErrorResponse(c, err)
+	}
+	return c.JSON(result)
+}
+
+// ===================== REVOCATION =====================
+// @Summary      Token revocation
+// @Description  RFC 7009. Revokes a token issued to the calling client: a refresh token ends its session, an access token is denylisted. Unknown or invalid tokens are ignored.
+// @Tags         OIDC
+// @Accept       x-www-form-urlencoded
+// @Produce      json
+// @Param        token            formData  string  true   "Access or refresh token"
+// @Param        token_type_hint  formData  string  false  "access_token or refresh_token"
+// @Param        client_id        formData  string  false  "Client ID"
+// @Param        client_secret    formData  string  false  "Client secret"
+// @Success      200
+// @Failure      400 {object} map[string]string
+// @Failure      401 {object} map[string]string
+// @Router       /oauth2/revoke [post]
+func Revoke(c *fiber.Ctx) error {
+	var req validation.RevokeRequest
+	if err := c.BodyParser(&req); err != nil || req.Token == "" {
+		return oauthErrorResponse(c, &service.OAuthError{Code: "invalid_request", Description: "token is required"})
+	}
+
+	client, err := service.AuthenticateOAuthClient(clientCredentials(c, req.ClientID, req.ClientSecret))
+	if err != nil {
+		return oauthErrorResponse(c, err)
+	}
+
+	if err := service.RevokeToken(client, req.Token); err != nil {
+		return oauthErrorResponse(c, err)
+	}
+	return c.SendStatus(fiber.StatusOK)
+}
+
+// ===================== USERINFO =====================
+// @Summary      UserInfo endpoint
+// @Description  Claims about the user of the access token, limited to the scopes granted to the client
+// @Tags         OIDC
+// @Produce      json
+// @Security     Bearer
+// @Success      200 {object} map[string]interface{}
+// @Failure      401 {object} map[string]string
+// @Router       /oauth2/userinfo [get]
+func UserInfo(c *fiber.Ctx) error {
+	user, err := service.GetUserByID(c.Locals("userID").(string))
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "invalid_token"})
+	}
+
+	// Tokens from the regular login are not limited to a client's scopes
+	scope := strings.Join(service.OIDCScopes, " ")
+	if sessionID, _ := c.Locals("sessionID").(string); sessionID != "" {
+		if session, err := service.GetSession(sessionID); err == nil && session.ClientID != "" {
+			scope = session.Scope
+		}
+	}
+	return c.JSON(service.UserInfoClaims(&user, scope))
+}
+
+// ===================== CLIENTS =====================
+// @Summary      List OIDC clients
+// @Description  List the applications registered for single sign-on
+// @Tags         OIDC
+// @Produce      json
+// @Security     Bearer
+// @Success      200 {object} res.Response{data=[]model.OAuthClient}
+// @Failure      500 {object} res.Response
+// @Router       /oauth2/clients [get]
+func GetOAuthClients(c *fiber.Ctx) error {
+	clients, err := service.GetOAuthClients()
+	if err != nil {
+		return utils.InternalError(c, err)
+	}
+	return c.JSON(res.SuccessResponse("Clients fetched successfully", clients))
+}
+
+// @Summary      Register OIDC client
+// @Description  Register an application for single sign-on. Confidential clients receive a secret that is only shown in this response; public clients (SPAs, mobile apps) rely on PKCE.
+// @Tags         OIDC
+// @Accept       json
+// @Produce      json
+// @Security     Bearer
+// @Param        payload  body      validation.CreateOAuthClientRequest  true  "Client data"
+// @Success      201 {object} res.Response{data=map[string]interface{}}
+// @Failure      400 {object} res.Response
+// @Failure      500 {object} res.Response
+// @Router       /oauth2/clients [post]
+func CreateOAuthClient(c *fiber.Ctx) error {
+	var req validation.CreateOAuthClientRequest
+	if err := c.BodyParser(&req); err != nil {
+		return utils.ValidationError(c, err)
+	}
+	if err := validation.ValidateStruct(&req); err != nil {
+		return utils.ValidationError(c, err)
+	}
+
+	client := model.OAuthClient{
+		Name:         strings.TrimSpace(req.Name),
+		RedirectURIs: req.RedirectURIs,
+	}
+	secret, err := service.CreateOAuthClient(&client, req.Confidential)
+	if err != nil {
+		return utils.InternalError(c, err)
+	}
+
+	data := fiber.Map{"client": client}
+	if secret != "" {
+		data["client_secret"] = secret
+	}
+	return c.Status(fiber.StatusCreated).JSON(res.SuccessResponse("Client registered successfully", data))
+}
+
+// @Summary      Delete OIDC client
+// @Description  Remove a client and end every session it created
+// @Tags         OIDC
+// @Produce      json
+// @Security     Bearer
+// @Param        client_id  path      string  true  "Client ID"
+// @Success      200 {object} res.Response
+// @Failure      404 {object} res.Response
+// @Failure      500 {object} res.Response
+// @Router       /oauth2/clients/{client_id} [delete]
+func DeleteOAuthClient(c *fiber.Ctx) error {
+	found, err := service.DeleteOAuthClient(c.Params("client_id"))
+	if err != nil {
+		return utils.InternalError(c, err)
+	}
+	if !found {
+		return c.Status(fiber.StatusNotFound).JSON(res.ErrorResponse("Client not found", nil))
+	}
+	return c.JSON(res.SuccessResponse("Client deleted successfully", nil))
+}
+
+func authorizationRequest(req validation.AuthorizeRequest) service.AuthorizationRequest {
+	return service.AuthorizationRequest{
+		ResponseType:        req.ResponseType,
+		ClientID:            req.ClientID,
+		RedirectURI:         req.RedirectURI,
+		Scope:               req.Scope,
+		State:               req.State,
+		Nonce:               req.Nonce,
+		CodeChallenge:       req.CodeChallenge,
+		CodeChallengeMethod: req.CodeChallengeMethod,
+	}
+}
+
+// authorizationError reports an invalid authorization request. Errors are
+// only sent back to the client once its redirect URI has been verified.
+func authorizationError(c *fiber.Ctx, req service.AuthorizationRequest, err error, redirect bool) error {
+	if errors.Is(err, service.ErrRedirectURIMismatch) {
+		return c.Status(fiber.StatusBadRequest).JSON(res.ErrorResponse("Unknown client or unregistered redirect_uri", nil))
+	}
+
+	var oauthErr *service.OAuthError
+	if !errors.As(err, &oauthErr) {
+		return utils.InternalError(c, err)
+	}
+	target := service.AuthorizationRedirect(req.RedirectURI, url.Values{
+		"error":             {oauthErr.Code},
+		"error_description": {oauthErr.Description},
+	}, req.State)
+
+	if redirect {
+		return c.Redirect(target, fiber.StatusFound)
+	}
+	return c.JSON(res.SuccessResponse("Authorization denied", fiber.Map{"redirect_to": target}))
+}
+
+// oauthErrorResponse writes an RFC 6749 section 5.2 error
+func oauthErrorResponse(c *fiber.Ctx, err error) error {
+	var oauthErr *service.OAuthError
+	if !errors.As(err, &oauthErr) {
+		return utils.InternalError(c, err)
+	}
+
+	status := fiber.StatusBadRequest
+	if oauthErr.Code == "invalid_client" {
+		status = fiber.StatusUnauthorized
+		c.Set(fiber.HeaderWWWAuthenticate, `Basic realm="oauth2"`)
+	}
+	return c.Status(status).JSON(fiber.Map{
+		"error":             oauthErr.Code,
+		"error_description": oauthErr.Description,
+	})
+}
+
+// clientCredentials reads client_secret_basic credentials, falling back to
+// the request body (client_secret_post, or client_id only for public clients).
+func clientCredentials(c *fiber.Ctx, bodyID string, bodySecret string) (string, string) {
+	header := c.Get(fiber.HeaderAuthorization)
+	if len(header) > 6 && strings.EqualFold(header[:6], "Basic ") {
+		if raw, err := base64.StdEncoding.DecodeString(header[6:]); err == nil {
+			if id, secret, ok := strings.Cut(string(raw), ":"); ok {
+				id, _ = url.QueryUnescape(id)
+				secret, _ = url.QueryUnescape(secret)
+				return id, secret
+			}
+		}
+	}
+	return bodyID, bodySecret
+}
@@ -0,0 +1,141 @@
+package controller
+
+import (
+	"errors"
+	"go-journey/src/password"
+	"go-journey/src/res"
+	"go-journey/src/service"
+	"go-journey/src/utils"
+	"go-journey/src/validation"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// ===================== FORGOT PASSWORD =====================
+// @Summary Request a password reset
+// @Description Email a single-use password reset link. The response is the same whether or not the account exists.
+// @Tags Auth
+// @Accept json
+// @Produce json
+// @Param payload body validation.ForgotPasswordRequest true "Forgot password payload"
+// @Success 200 {object} res.Response
+// @Failure 400 {object} res.Response
+// @Failure 429 {object} res.Response
+// @Failure 500 {object} res.Response
+// @Router /auth/forgot-password [post]
+func ForgotPassword(c *fiber.Ctx) error {
+	var req validation.ForgotPasswordRequest
+	if err := c.BodyParser(&req); err != nil {
+		return utils.ValidationError(c, err)
+	}
+	if err := validation.ValidateStruct(req); err != nil {
+		return utils.ValidationError(c, err)
+	}
+
+	if err := service.RequestPasswordReset(strings.TrimSpace(req.Username)); err != nil {
+		return utils.InternalError(c, err)
+	}
+
+	return c.JSON(res.SuccessResponse("If the account exists, a password reset link has been sent", nil))
+}
+
+// ===================== RESET PASSWORD =====================
+// @Summary Reset password
+// @Description Set a new password with a reset token. All sessions of the user are revoked.
+// @Tags Auth
+// @Accept json
+// @Produce json
+// @Param payload body validation.ResetPasswordRequest true "Reset password payload"
+// @Success 200 {object} res.Response
+// @Failure 400 {object} res.Response
+// @Failure 429 {object} res.Response
+// @Failure 500 {object} res.Response
+// @Router /auth/reset-password [post]
+func ResetPassword(c *fiber.Ctx) error {
+	var req validation.ResetPasswordRequest
+	if err := c.BodyParser(&req); err != nil {
+		return utils.ValidationError(c, err)
+	}
+	if err := validation.ValidateStruct(req); err != nil {
+		return utils.ValidationError(c, err)
+	}
+
+	if err := service.ResetPassword(req.Token, req.Password); err != nil {
+		if errors.Is(err, service.ErrInvalidUserToken) {
+			return c.Status(fiber.StatusBadRequest).JSON(res.ErrorResponse("Invalid or expired reset token", nil))
+		}
+		return passwordPolicyError(c, err)
+	}
+
+	return c.JSON(res.SuccessResponse("Password reset successfully, please log in again", nil))
+}
+
+// ===================== VERIFY EMAIL =====================
+// @Summary Verify email address
+// @Description Confirm the email address with the token from the verification email
+// @Tags Auth
+// @Accept json
+// @Produce json
+// @Param payload body validation.VerifyEmailRequest true "Verify email payload"
+// @Success 200 {object} res.Response
+// @Failure 400 {object} res.Response
+// @Failure 429 {object} res.Response
+// @Failure 500 {object} res.Response
+// @Router /auth/verify-email [post]
+func VerifyEmail(c *fiber.Ctx) error {
+	var req validation.VerifyEmailRequest
+	if err := c.BodyParser(&req); err != nil {
+		return utils.ValidationError(c, err)
+	}
+	if err := validation.ValidateStruct(req); err != nil {
+		return utils.ValidationError(c, err)
+	}
+
+	if err := service.VerifyEmail(req.Token); err != nil {
+		if errors.Is(err, service.ErrInvalidUserToken) {
+			return c.Status(fiber.StatusBadRequest).JSON(res.ErrorResponse("Invalid or expired verification token", nil))
+		}
+		return utils.InternalError(c, err)
+	}
+
+	return c.JSON(res.SuccessResponse("Email verified successfully", nil))
+}
+
+// ===================== RESEND VERIFICATION =====================
+// @Summary Resend verification email
+// @Description Send a new verification link. The response is the same whether or not the address is registered.
+// @Tags Auth
+// @Accept json
+// @Produce json
+// @Param payload body validation.ResendVerificationRequest true "Resend verification payload"
+// @Success 200 {object} res.Response
+// @Failure 400 {object} res.Response
+// @Failure 429 {object} res.Response
+// @Failure 500 {object} res.Response
+// @Router /auth/resend-verification [post]
+func ResendVerification(c *fiber.Ctx) error {
+	var req validation.ResendVerificationRequest
+	if err := c.BodyParser(&req); err != nil {
+		return utils.ValidationError(c, err)
+	}
+	if err := validation.ValidateStruct(req); err != nil {
+		return utils.ValidationError(c, err)
+	}
+
+	if err := service.ResendEmailVerification(req.Email); err != nil {
+		return utils.InternalError(c, err)
+	}
+
+	return c.JSON(res.SuccessResponse("If the address is registered and unverified, a verification link has been sent", nil))
+}
+
+// passwordPolicyError answers with the policy violation of a rejected
+// password, or a 500 for any other error.
+func passwordPolicyError(c *fiber.Ctx, err error) error {
+	var policyErr *password.PolicyError
+	if errors.As(err, &policyErr) {
+		return c.Status(fiber.StatusBadRequest).JSON(res.ErrorCodeResponse("password_policy", policyErr.Message))
+	}
+	return utils.InternalError(c, err)
+}
@@ -0,0 +1,280 @@
+package controller
+
+import (
+	"errors"
+	"go-journey/src/model"
+	"go-journey/src/res"
+	"go-journey/src/service"
+	"go-journey/src/utils"
+	"go-journey/src/validation"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// @Summary      Get all roles
+// @Description  List roles with the permissions they grant
+// @Tags         roles
+// @Produce      json
+// @Security Bearer
+// @Success      200 {object} res.Response{data=[]model.Role}
+// @Failure      500 {object} res.Response
+// @Router       /roles [get]
+func GetRoles(c *fiber.Ctx) error {
+	roles, err := service.GetAllRoles()
+	if err != nil {
+		return utils.InternalError(c, err)
+	}
+	return c.JSON(res.SuccessResponse("Roles fetched successfully", roles))
+}
+
+// @Summary      Get role by ID
+// @Description  Get a role and its permissions
+// @Tags         roles
+// @Produce      json
+// @Security Bearer
+// @Param        id   path      string  true  "Role UUID"
+// @Success      200 {object} res.Response{data=model.Role}
+// @Failure      404 {object} res.Response
+// @Failure      500 {object} res.Response
+// @Router       /roles/{id} [get]
+func GetRole(c *fiber.Ctx) error {
+	role, err := service.GetRoleByID(c.Params("id"))
+	if err != nil {
+		return roleLookupError(c, err)
+	}
+	return c.JSON(res.SuccessResponse("Role fetched successfully", role))
+}
+
+// @Summary      Create role
+// @Description  Create a role, optionally granting permissions
+// @Tags         roles
+// @Accept       json
+// @Produce      json
+// @Security Bearer
+// @Param        role  body      validation.CreateRoleRequest  true  "Role data"
+// @Success      201 {object} res.Response{data=model.Role}
+// @Failure      400 {object} res.Response
+// @Failure      500 {object} res.Response
+// @Router       /roles [post]
+func CreateRole(c *fiber.Ctx) error {
+	var req validation.CreateRoleRequest
+	if err := c.BodyParser(&req); err != nil {
+		return utils.ValidationError(c, err)
+	}
+	if err := validation.ValidateStruct(&req); err != nil {
+		return utils.ValidationError(c, err)
+	}
+
+	req.Name = strings.ToLower(strings.TrimSpace(req.Name))
+	if service.RoleExists(req.Name) {
+		return c.Status(fiber.StatusBadRequest).JSON(res.ErrorResponse("Role already exists", nil))
+	}
+
+	permissions, err := service.GetPermissionsByName(req.Permissions)
+	if err != nil {
+		return permissionLookupError(c, err)
+	}
+
+	role := model.Role{
+		Name:        req.Name,
+		Description: req.Description,
+		Permissions: permissions,
+	}
+	if err := service.CreateRole(&role); err != nil {
+		return utils.InternalError(c, err)
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(res.SuccessResponse("Role created successfully", role))
+}
+
+// @Summary      Update role
+// @Description  Update the description of a role. Role names are immutable because users reference them.
+// @Tags         roles
+// @Accept       json
+// @Produce      json
+// @Security Bearer
+// @Param        id    path      string                        true  "Role UUID"
+// @Param        role  body      validation.UpdateRoleRequest  true  "Role data"
+// @Success      200 {object} res.Response{data=model.Role}
+// @Failure      400 {object} res.Response
+// @Failure      404 {object} res.Response
+// @Failure      500 {object} res.Response
+// @Router       /roles/{id} [put]
+func UpdateRole(c *fiber.Ctx) error {
+	var req validation.UpdateRoleRequest
+	if err := c.BodyParser(&req); err != nil {
+		return utils.ValidationError(c, err)
+	}
+	if err := validation.ValidateStruct(&req); err != nil {
+		return utils.ValidationError(c, err)
+	}
+
+	role, err := service.GetRoleByID(c.Params("id"))
+	if err != nil {
+		return roleLookupError(c, err)
+	}
+
+	role.Description = req.Description
+	if err := service.UpdateRole(&role); err != nil {
+		return utils.InternalError(c, err)
+	}
+
+	return c.JSON(res.SuccessResponse("Role updated successfully", role))
+}
+
+// @Summary      Set role permissions
+// @Description  Replace the permissions granted by a role
+// @Tags         roles
+// @Accept       json
+// @Produce      json
+// @Security Bearer
+// @Param        id           path      string                                true  "Role UUID"
+// @Param        permissions  body      validation.SetRolePermissionsRequest  true  "Permission names"
+// @Success      200 {object} res.Response{data=model.Role}
+// @Failure      400 {object} res.Response
+// @Failure      404 {object} res.Response
+// @Failure      500 {object} res.Response
+// @Router       /roles/{id}/permissions [put]
+func SetRolePermissions(c *fiber.Ctx) error {
+	var req validation.SetRolePermissionsRequest
+	if err := c.BodyParser(&req); err != nil {
+		return utils.ValidationError(c, err)
+	}
+	if err := validation.ValidateStruct(&req); err != nil {
+		return utils.ValidationError(c, err)
+	}
+
+	role, err := service.GetRoleByID(c.Params("id"))
+	if err != nil {
+		return roleLookupError(c, err)
+	}
+
+	permissions, err := service.GetPermissionsByName(req.Permissions)
+	if err != nil {
+		return permissionLookupError(c, err)
+	}
+
+	if err := service.SetRolePermissions(&role, permissions); err != nil {
+		return utils.InternalError(c, err)
+	}
+	role.Permissions = permissions
+
+	return c.JSON(res.SuccessResponse("Role permissions updated successfully", role))
+}
+
+// @Summary      Delete role
+// @Description  Delete a role that is no longer assigned to any user
+// @Tags         roles
+// @Produce      json
+// @Security Bearer
+// @Param        id   path      string  true  "Role UUID"
+// @Success      200 {object} res.Response
+// @Failure      400 {object} res.Response
+// @Failure      404 {object} res.Response
+// @Failure      500 {object} res.Response
+// @Router       /roles/{id} [delete]
+func DeleteRole(c *fiber.Ctx) error {
+	role, err := service.GetRoleByID(c.Params("id"))
+	if err != nil {
+		return roleLookupError(c, err)
+	}
+
+	if err := service.DeleteRole(&role); err != nil {
+		if errors.Is(err, service.ErrRoleInUse) {
+			return c.Status(fiber.StatusBadRequest).JSON(res.ErrorResponse("Role is still assigned to users", nil))
+		}
+		return utils.InternalError(c, err)
+	}
+
+	return c.JSON(res.SuccessResponse("Role deleted successfully", nil))
+}
+
+// @Summary      Get all permissions
+// @Description  List every permission that can be granted to roles
+// @Tags         roles
+// @Produce      json
+// @Security Bearer
+// @Success      200 {object} res.Response{data=[]model.Permission}
+// @Failure      500 {object} res.Response
+// @Router       /permissions [get]
+func GetPermissions(c *fiber.Ctx) error {
+	permissions, err := service.GetAllPermissions()
+	if err != nil {
+		return utils.InternalError(c, err)
+	}
+	return c.JSON(res.SuccessResponse("Permissions fetched successfully", permissions))
+}
+
+// @Summary      Create permission
+// @Description  Define a new permission, e.g. "reports:read"
+// @Tags         roles
+// @Accept       json
+// @Produce      json
+// @Security Bearer
+// @Param        permission  body      validation.CreatePermissionRequest  true  "Permission data"
+// @Success      201 {object} res.Response{data=model.Permission}
+// @Failure      400 {object} res.Response
+// @Failure      500 {object} res.Response
+// @Router       /permissions [post]
+func CreatePermission(c *fiber.Ctx) error {
+	var req validation.CreatePermissionRequest
+	if err := c.BodyParser(&req); err != nil {
+		return utils.ValidationError(c, err)
+	}
+	if err := validation.ValidateStruct(&req); err != nil {
+		return utils.ValidationError(c, err)
+	}
+
+	req.Name = strings.TrimSpace(req.Name)
+	if _, err := service.GetPermissionsByName([]string{req.Name}); err == nil {
+		return c.Status(fiber.StatusBadRequest).JSON(res.ErrorResponse("Permission already exists", nil))
+	}
+
+	permission := model.Permission{Name: req.Name, Description: req.Description}
+	if err := service.CreatePermission(&permission); err != nil {
+		return utils.InternalError(c, err)
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(res.SuccessResponse("Permission created successfully", permission))
+}
+
+// @Summary      Delete permission
+// @Description  Delete a permission and revoke it from every role
+// @Tags         roles
+// @Produce      json
+// @Security Bearer
+// @Param        id   path      string  true  "Permission UUID"
+// @Success      200 {object} res.Response
+// @Failure      404 {object} res.Response
+// @Failure      500 {object} res.Response
+// @Router       /permissions/{id} [delete]
+func DeletePermission(c *fiber.Ctx) error {
+	permission, err := service.GetPermissionByID(c.Params("id"))
+	if err != nil {
+		if err.Error() == "record not found" {
+			return c.Status(fiber.StatusNotFound).JSON(res.ErrorResponse("Permission not found", nil))
+		}
+		return utils.InternalError(c, err)
+	}
+
+	if err := service.DeletePermission(&permission); err != nil {
+		return utils.InternalError(c, err)
+	}
+
+	return c.JSON(res.SuccessResponse("Permission deleted successfully", nil))
+}
+
+func roleLookupError(c *fiber.Ctx, err error) error {
+	if err.Error() == "record not found" {
+		return c.Status(fiber.StatusNotFound).JSON(res.ErrorResponse("Role not found", nil))
+	}
+	return utils.InternalError(c, err)
+}
+
+func permissionLookupError(c *fiber.Ctx, err error) error {
+	if errors.Is(err, service.ErrUnknownPermission) {
+		return c.Status(fiber.StatusBadRequest).JSON(res.ErrorResponse("Unknown permission provided", nil))
+	}
+	return utils.InternalError(c, err)
+}
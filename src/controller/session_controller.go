@@ -0,0 +1,87 @@
+package controller
+
+import (
+	"go-journey/src/res"
+	"go-journey/src/service"
+	"go-journey/src/utils"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// @Summary      List active sessions
+// @Description  List the devices the current user is logged in on
+// @Tags         Auth
+// @Produce      json
+// @Security     Bearer
+// @Success      200 {object} res.Response{data=[]map[string]interface{}}
+// @Failure      401 {object} res.Response
+// @Failure      500 {object} res.Response
+// @Router       /auth/sessions [get]
+func GetSessions(c *fiber.Ctx) error {
+	userID := c.Locals("userID").(string)
+	currentID, _ := c.Locals("sessionID").(string)
+
+	sessions, err := service.GetActiveSessions(userID)
+	if err != nil {
+		return utils.InternalError(c, err)
+	}
+
+	data := make([]fiber.Map, 0, len(sessions))
+	for _, s := range sessions {
+		data = append(data, fiber.Map{
+			"id":           s.ID,
+			"device_name":  s.DeviceName,
+			"user_agent":   s.UserAgent,
+			"ip":           s.IP,
+			"created_at":   s.CreatedAt,
+			"last_used_at": s.LastUsedAt,
+			"expires_at":   s.ExpiresAt,
+			"current":      s.ID == currentID,
+		})
+	}
+
+	return c.JSON(res.SuccessResponse("Sessions fetched successfully", data))
+}
+
+// @Summary      Revoke a session
+// @Description  Log out one of the current user's devices
+// @Tags         Auth
+// @Produce      json
+// @Security     Bearer
+// @Param        id   path      string  true  "Session UUID"
+// @Success      200 {object} res.Response
+// @Failure      404 {object} res.Response
+// @Failure      500 {object} res.Response
+// @Router       /auth/sessions/{id} [delete]
+func RevokeSession(c *fiber.Ctx) error {
+	userID := c.Locals("userID").(string)
+
+	found, err := service.RevokeSession(userID, c.Params("id"))
+	if err != nil {
+		return utils.InternalError(c, err)
+	}
+	if !found {
+		return c.Status(fiber.StatusNotFound).JSON(res.ErrorResponse("Session not found", nil))
+	}
+
+	return c.JSON(res.SuccessResponse("Session revoked successfully", nil))
+}
+
+// @Summary      Log out everywhere
+// @Description  Revoke every session of the current user, including this one
+// @Tags         Auth
+// @Produce      json
+// @Security     Bearer
+// @Success      200 {object} res.Response
+// @Failure      500 {object} res.Response
+// @Router       /auth/logout-all [post]
+func LogoutAll(c *fiber.Ctx) error {
+	userID := c.Locals("userID").(string)
+
+	if err := service.RevokeAllSessions(userID); err != nil {
+		return utils.InternalError(c, err)
+	}
+	clearCookieSession(c)
+
+	return c.JSON(res.SuccessResponse("Logged out from all devices", fiber.Map{}))
+}
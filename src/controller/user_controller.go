@@ -1,43 +1,155 @@
 package controller
 
 import (
+	"errors"
 	"go-journey/src/model"
+	"go-journey/src/password"
 	"go-journey/src/res"
 	"go-journey/src/service"
 	"go-journey/src/utils"
 	"go-journey/src/validation"
+	"log"
+	"slices"
+	"strings"
+	"time"
 
 	"github.com/gofiber/fiber/v2"
-	"golang.org/x/crypto/bcrypt"
 )
 
 // @Summary      Get all users
-// @Description  Get list of users
+// @Description  Get a page of users. Use page/per_page for numbered pages or
+// @Description  cursor (the previous page's meta.next_cursor) for stable paging.
+// @Description  Callers with users:unlock also get each user's lockout state.
 // @Tags         users
 // @Produce      json
-// @Success      200 {object} res.Response{data=[]model.User}
+// @Security Bearer
+// @Param        page             query  int     false  "Page number (default 1)"
+// @Param        per_page         query  int     false  "Users per page (default 20, max 100)"
+// @Param        cursor           query  string  false  "Cursor from the previous page"
+// @Param        sort             query  string  false  "username, full_name, email, role or register_date; prefix - for descending (default -register_date)"
+// @Param        q                query  string  false  "Search username and full name"
+// @Param        role             query  string  false  "Filter by role"
+// @Param        status           query  string  false  "Filter by status"
+// @Param        esign_status_id  query  string  false  "Filter by e-sign status"
+// @Param        registered_from  query  string  false  "Registered on or after (YYYY-MM-DD)"
+// @Param        registered_to    query  string  false  "Registered on or before (YYYY-MM-DD)"
+// @Success      200 {object} res.Response{data=[]res.UserResponse,meta=res.Pagination}
+// @Failure      400 {object} res.Response
+// @Failure      401 {object} res.Response
+// @Failure      403 {object} res.Response
 // @Failure      500 {object} res.Response
 // @Router       /users [get]
 func GetUsers(c *fiber.Ctx) error {
-	users, err := service.GetAllUsers()
+	var req validation.ListUsersRequest
+	if err := c.QueryParser(&req); err != nil {
+		return utils.ValidationError(c, err)
+	}
+	if err := validation.ValidateStruct(&req); err != nil {
+		return utils.ValidationError(c, err)
+	}
+
+	filter := service.UserFilter{
+		Page:          req.Page,
+		PerPage:       req.PerPage,
+		Cursor:        req.Cursor,
+		Sort:          req.Sort,
+		Query:         req.Query,
+		Role:          req.Role,
+		Status:        req.Status,
+		EsignStatusID: req.EsignStatusID,
+	}
+	if filter.Page == 0 {
+		filter.Page = 1
+	}
+	if filter.PerPage == 0 {
+		filter.PerPage = 20
+	}
+	if req.RegisteredFrom != "" {
+		from, _ := time.Parse("2006-01-02", req.RegisteredFrom)
+		filter.RegisteredFrom = &from
+	}
+	if req.RegisteredTo != "" {
+		// The end date is inclusive
+		to, _ := time.Parse("2006-01-02", req.RegisteredTo)
+		to = to.AddDate(0, 0, 1)
+		filter.RegisteredTo = &to
+	}
+
+	page, err := service.ListUsers(filter)
+	if errors.Is(err, service.ErrInvalidSort) {
+		return c.Status(fiber.StatusBadRequest).JSON(res.ErrorResponse("Invalid sort field", err))
+	}
+	if errors.Is(err, service.ErrInvalidCursor) {
+		return c.Status(fiber.StatusBadRequest).JSON(res.ErrorResponse("Invalid cursor", err))
+	}
 	if err != nil {
 		return utils.InternalError(c, err)
 	}
 
-	for i := range users {
-		users[i].Password = ""
+	meta := res.Pagination{
+		PerPage:    filter.PerPage,
+		HasMore:    page.HasMore,
+		NextCursor: page.NextCursor,
+	}
+	if page.Total != nil {
+		totalPages := int((*page.Total + int64(filter.PerPage) - 1) / int64(filter.PerPage))
+		meta.Page = filter.Page
+		meta.Total = page.Total
+		meta.TotalPages = &totalPages
 	}
 
-	return c.JSON(res.SuccessResponse("Users fetched successfully", users))
+	users := res.NewUserResponses(page.Users)
+	if err := addLockouts(c, users); err != nil {
+		return utils.InternalError(c, err)
+	}
+
+	return c.JSON(res.PaginatedResponse("Users fetched successfully", users, meta))
+}
+
+// @Summary      Search users
+// @Description  Typo-tolerant search over username and full name, best matches first.
+// @Description  highlight holds HTML-escaped fields with matching words wrapped in <mark>.
+// @Tags         users
+// @Produce      json
+// @Security Bearer
+// @Param        q      query  string  true   "Search text"
+// @Param        limit  query  int     false  "Maximum results (default 10, max 50)"
+// @Success      200 {object} res.Response{data=[]service.UserSearchResult}
+// @Failure      400 {object} res.Response
+// @Failure      401 {object} res.Response
+// @Failure      403 {object} res.Response
+// @Failure      500 {object} res.Response
+// @Router       /users/search [get]
+func SearchUsers(c *fiber.Ctx) error {
+	var req validation.SearchUsersRequest
+	if err := c.QueryParser(&req); err != nil {
+		return utils.ValidationError(c, err)
+	}
+	if err := validation.ValidateStruct(&req); err != nil {
+		return utils.ValidationError(c, err)
+	}
+	if req.Limit == 0 {
+		req.Limit = 10
+	}
+
+	results, err := service.SearchUsers(req.Query, req.Limit)
+	if err != nil {
+		return utils.InternalError(c, err)
+	}
+
+	return c.JSON(res.SuccessResponse("Users fetched successfully", results))
 }
 
 // @Summary      Get user by ID
-// @Description  Get user detail by ID (UUID)
+// @Description  Get user detail by ID (UUID). Callers with users:unlock also get the lockout state.
 // @Tags         users
 // @Produce      json
+// @Security Bearer
 // @Param        id   path      string  true  "User UUID"
-// @Success      200 {object} res.Response{data=model.User}
+// @Success      200 {object} res.Response{data=res.UserResponse}
 // @Failure      400 {object} res.Response
+// @Failure      401 {object} res.Response
+// @Failure      403 {object} res.Response
 // @Failure      404 {object} res.Response
 // @Failure      500 {object} res.Response
 // @Router       /users/{id} [get]
@@ -56,8 +168,39 @@ func GetUser(c *fiber.Ctx) error {
 		return utils.InternalError(c, err)
 	}
 
-	user.Password = "" // hide password
-	return c.JSON(res.SuccessResponse("User fetched successfully", user))
+	users := []res.UserResponse{res.NewUserResponse(&user)}
+	if err := addLockouts(c, users); err != nil {
+		return utils.InternalError(c, err)
+	}
+
+	return c.JSON(res.SuccessResponse("User fetched successfully", users[0]))
+}
+
+// addLockouts fills in the lockout state of the users when the caller may
+// unlock accounts
+func addLockouts(c *fiber.Ctx, users []res.UserResponse) error {
+	role, _ := c.Locals("role").(string)
+	if scopes, scoped := c.Locals("scopes").([]string); scoped && !slices.Contains(scopes, model.PermUsersUnlock) {
+		return nil
+	}
+	allowed, err := service.RoleHasPermission(role, model.PermUsersUnlock)
+	if err != nil || !allowed {
+		return err
+	}
+
+	ids := make([]string, len(users))
+	for i := range users {
+		ids[i] = users[i].ID
+	}
+	throttles, err := service.GetAccountThrottles(ids...)
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	for i := range users {
+		users[i].Lockout = res.NewUserLockout(throttles[users[i].ID], now)
+	}
+	return nil
 }
 
 // @Summary      Create new user
@@ -67,8 +210,9 @@ func GetUser(c *fiber.Ctx) error {
 // @Produce      json
 // @Security Bearer
 // @Param        user  body      validation.CreateUserRequest  true  "User data"
-// @Success      201 {object} res.Response{data=model.User}
+// @Success      201 {object} res.Response{data=res.UserResponse}
 // @Failure      400 {object} res.Response
+// @Failure      403 {object} res.Response
 // @Failure      500 {object} res.Response
 // @Router       /users [post]
 func CreateUser(c *fiber.Ctx) error {
@@ -80,26 +224,51 @@ func CreateUser(c *fiber.Ctx) error {
 	if err := validation.ValidateStruct(&req); err != nil {
 		return utils.ValidationError(c, err)
 	}
-
-	hashed, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
-	if err != nil {
-		return utils.InternalError(c, err)
+	if req.Role != "" && !service.RoleExists(req.Role) {
+		return c.Status(fiber.StatusBadRequest).JSON(res.ErrorResponse("Invalid role provided", nil))
+	}
+	if req.Role != "" {
+		allowed, err := canAssignRole(c, req.Role)
+		if err != nil {
+			return utils.InternalError(c, err)
+		}
+		if !allowed {
+			return c.Status(fiber.StatusForbidden).JSON(res.ErrorResponse("Your role does not grant every permission of "+req.Role, nil))
+		}
+	}
+	if service.EmailTaken(req.Email, "") {
+		return c.Status(fiber.StatusBadRequest).JSON(res.ErrorResponse("Email already used", nil))
 	}
 
 	user := model.User{
 		Username: req.Username,
+		Email:    service.NormalizeEmail(req.Email),
 		FullName: req.FullName,
-		Password: string(hashed),
 		Role:     req.Role,
 	}
+	if err := service.CheckNewPassword(&user, req.Password); err != nil {
+		return passwordPolicyError(c, err)
+	}
+
+	hashed, err := password.Hash(req.Password)
+	if err != nil {
+		return utils.InternalError(c, err)
+	}
+	user.Password = hashed
 
 	if err := service.CreateUser(&user); err != nil {
 		return utils.InternalError(c, err)
 	}
+	if err := service.RecordPasswordHistory(user.ID, user.Password); err != nil {
+		return utils.InternalError(c, err)
+	}
+
+	if err := service.SendEmailVerification(&user); err != nil {
+		log.Println("[CreateUser] failed to send verification email:", err)
+	}
 
-	user.Password = "" // hide password
 	return c.Status(fiber.StatusCreated).
-		JSON(res.SuccessResponse("User created successfully", user))
+		JSON(res.SuccessResponse("User created successfully", res.NewUserResponse(&user)))
 }
 
 // @Summary      Update user
@@ -109,8 +278,9 @@ func CreateUser(c *fiber.Ctx) error {
 // @Produce      json
 // @Param        id    path      string                        true  "User UUID"
 // @Param        user  body      validation.UpdateUserRequest  true  "User data"
-// @Success      200 {object} res.Response{data=model.User}
+// @Success      200 {object} res.Response{data=res.UserResponse}
 // @Failure      400 {object} res.Response
+// @Failure      403 {object} res.Response
 // @Failure      404 {object} res.Response
 // @Failure      500 {object} res.Response
 // @Router       /users/{id} [put]
@@ -128,6 +298,9 @@ func UpdateUser(c *fiber.Ctx) error {
 	if err := validation.ValidateStruct(&req); err != nil {
 		return utils.ValidationError(c, err)
 	}
+	if req.Role != "" && !service.RoleExists(req.Role) {
+		return c.Status(fiber.StatusBadRequest).JSON(res.ErrorResponse("Invalid role provided", nil))
+	}
 
 	user, err := service.GetUserByID(id)
 	if err != nil {
@@ -138,6 +311,20 @@ func UpdateUser(c *fiber.Ctx) error {
 		return utils.InternalError(c, err)
 	}
 
+	// Only users whose role, current and requested, the caller fully holds can be changed
+	for _, role := range []string{user.Role, req.Role} {
+		if role == "" {
+			continue
+		}
+		allowed, err := canAssignRole(c, role)
+		if err != nil {
+			return utils.InternalError(c, err)
+		}
+		if !allowed {
+			return c.Status(fiber.StatusForbidden).JSON(res.ErrorResponse("Your role does not grant every permission of "+role, nil))
+		}
+	}
+
 	// Apply updates
 	if req.Username != "" {
 		user.Username = req.Username
@@ -145,13 +332,25 @@ func UpdateUser(c *fiber.Ctx) error {
 	if req.FullName != "" {
 		user.FullName = req.FullName
 	}
+	emailChanged := req.Email != "" && service.NormalizeEmail(req.Email) != user.Email
+	if emailChanged {
+		if service.EmailTaken(req.Email, user.ID) {
+			return c.Status(fiber.StatusBadRequest).JSON(res.ErrorResponse("Email already used", nil))
+		}
+		user.Email = service.NormalizeEmail(req.Email)
+		user.EmailVerifiedAt = nil
+	}
 	if req.Password != "" {
-		hashed, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
+		if err := service.CheckNewPassword(&user, req.Password); err != nil {
+			return passwordPolicyError(c, err)
+		}
+		hashed, err := password.Hash(req.Password)
 		if err != nil {
 			return utils.InternalError(c, err)
 		}
-		user.Password = string(hashed)
+		user.Password = hashed
 	}
+	roleChanged := req.Role != "" && req.Role != user.Role
 	if req.Role != "" {
 		user.Role = req.Role
 	}
@@ -160,8 +359,38 @@ func UpdateUser(c *fiber.Ctx) error {
 		return utils.InternalError(c, err)
 	}
 
-	user.Password = ""
-	return c.JSON(res.SuccessResponse("User updated successfully", user))
+	if emailChanged {
+		if err := service.RevokeUserTokens(user.ID, model.TokenPurposeEmailVerification); err != nil {
+			return utils.InternalError(c, err)
+		}
+		if err := service.SendEmailVerification(&user); err != nil {
+			log.Println("[UpdateUser] failed to send verification email:", err)
+		}
+	}
+
+	// A new password ends every session, a new role only the old access tokens
+	if req.Password != "" {
+		if err := service.RecordPasswordHistory(user.ID, user.Password); err != nil {
+			return utils.InternalError(c, err)
+		}
+		if err := service.InvalidateUserTokens(user.ID); err != nil {
+			return utils.InternalError(c, err)
+		}
+	} else if roleChanged {
+		if err := utils.BumpTokenVersion(user.ID); err != nil {
+			return utils.InternalError(c, err)
+		}
+	}
+
+	return c.JSON(res.SuccessResponse("User updated successfully", res.NewUserResponse(&user)))
+}
+
+// canAssignRole reports whether the caller's role, limited by API key scopes,
+// grants every permission of role, so that handing it out gains no rights
+func canAssignRole(c *fiber.Ctx, role string) (bool, error) {
+	actorRole, _ := c.Locals("role").(string)
+	scopes, _ := c.Locals("scopes").([]string)
+	return service.RoleGrantsRole(actorRole, role, scopes)
 }
 
 // @Summary      Delete user
@@ -193,6 +422,118 @@ func DeleteUser(c *fiber.Ctx) error {
 	if err := service.DeleteUser(id); err != nil {
 		return utils.InternalError(c, err)
 	}
+	if err := service.InvalidateUserTokens(id); err != nil {
+		return utils.InternalError(c, err)
+	}
 
 	return c.JSON(res.SuccessResponse("User deleted successfully", nil))
 }
+
+// @Summary      Impersonate user
+// @Description  Issue a short-lived access token to act as the user, for support. The token carries an "act" claim naming the admin, cannot be refreshed and is recorded in the security events.
+// @Tags         users
+// @Accept       json
+// @Produce      json
+// @Security Bearer
+// @Param        id       path      string                         true   "User UUID"
+// @Param        payload  body      validation.ImpersonateRequest  false  "Reason for the audit trail"
+// @Success      200 {object} res.Response{data=map[string]interface{}}
+// @Failure      400 {object} res.Response
+// @Failure      403 {object} res.Response
+// @Failure      404 {object} res.Response
+// @Failure      500 {object} res.Response
+// @Router       /users/{id}/impersonate [post]
+func ImpersonateUser(c *fiber.Ctx) error {
+	var req validation.ImpersonateRequest
+	if len(c.Body()) > 0 {
+		if err := c.BodyParser(&req); err != nil {
+			return utils.ValidationError(c, err)
+		}
+	}
+	if err := validation.ValidateStruct(&req); err != nil {
+		return utils.ValidationError(c, err)
+	}
+
+	target, err := service.GetUserByID(c.Params("id"))
+	if err != nil {
+		if err.Error() == "record not found" {
+			return c.Status(fiber.StatusNotFound).
+				JSON(res.ErrorResponse("User not found", nil))
+		}
+		return utils.InternalError(c, err)
+	}
+
+	actor, err := service.GetUserByID(c.Locals("userID").(string))
+	if err != nil {
+		return utils.InternalError(c, err)
+	}
+
+	impersonation, err := service.Impersonate(&actor, &target, sessionMeta(c, ""), strings.TrimSpace(req.Reason))
+	switch {
+	case errors.Is(err, service.ErrImpersonateSelf):
+		return c.Status(fiber.StatusBadRequest).JSON(res.ErrorResponse("You cannot impersonate yourself", nil))
+	case errors.Is(err, service.ErrImpersonationForbidden):
+		return c.Status(fiber.StatusForbidden).JSON(res.ErrorResponse("This user cannot be impersonated", nil))
+	case err != nil:
+		return utils.InternalError(c, err)
+	}
+
+	return c.JSON(res.SuccessResponse("Impersonation started", fiber.Map{
+		"user":        userPayload(&target),
+		"accessToken": impersonation.AccessToken,
+		"expiresAt":   impersonation.ExpiresAt,
+	}))
+}
+
+// @Summary      Deactivate user
+// @Description  Disable a user account and revoke all of its sessions and tokens
+// @Tags         users
+// @Produce      json
+// @Security Bearer
+// @Param        id   path      string  true  "User UUID"
+// @Success      200 {object} res.Response
+// @Failure      404 {object} res.Response
+// @Failure      500 {object} res.Response
+// @Router       /users/{id}/deactivate [post]
+func DeactivateUser(c *fiber.Ctx) error {
+	return setUserStatus(c, model.UserStatusDisabled, "User deactivated successfully")
+}
+
+// @Summary      Activate user
+// @Description  Re-enable a deactivated user account, or approve a pending registration
+// @Tags         users
+// @Produce      json
+// @Security Bearer
+// @Param        id   path      string  true  "User UUID"
+// @Success      200 {object} res.Response
+// @Failure      404 {object} res.Response
+// @Failure      500 {object} res.Response
+// @Router       /users/{id}/activate [post]
+func ActivateUser(c *fiber.Ctx) error {
+	return setUserStatus(c, model.UserStatusActive, "User activated successfully")
+}
+
+func setUserStatus(c *fiber.Ctx, status string, message string) error {
+	id := c.Params("id")
+
+	user, err := service.GetUserByID(id)
+	if err != nil {
+		if err.Error() == "record not found" {
+			return c.Status(fiber.StatusNotFound).
+				JSON(res.ErrorResponse("User not found", nil))
+		}
+		return utils.InternalError(c, err)
+	}
+
+	if err := service.SetUserStatus(id, status); err != nil {
+		return utils.InternalError(c, err)
+	}
+
+	if user.Status == model.UserStatusPending && status == model.UserStatusActive {
+		if err := service.SendAccountApproved(&user); err != nil {
+			log.Println("[ActivateUser] failed to send approval email:", err)
+		}
+	}
+
+	return c.JSON(res.SuccessResponse(message, nil))
+}
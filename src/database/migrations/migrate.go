@@ -10,10 +10,69 @@ import (
 func Migrate() {
 	log.Println("🚀 Running migration...")
 
-	err := database.DB.AutoMigrate(&model.User{})
+	err := database.DB.AutoMigrate(
+		&model.User{},
+		&model.Session{},
+		&model.RefreshToken{},
+		&model.SecurityEvent{},
+		&model.SigningKey{},
+		&model.DeniedToken{},
+		&model.Role{},
+		&model.Permission{},
+		&model.UserToken{},
+		&model.UserIdentity{},
+		&model.OAuthState{},
+		&model.RecoveryCode{},
+		&model.LoginThrottle{},
+		&model.AccountThrottle{},
+		&model.StorageEntry{},
+		&model.APIKey{},
+		&model.PasswordHistory{},
+		&model.OAuthClient{},
+		&model.OAuthAuthorizationCode{},
+		&model.Invitation{},
+	)
 	if err != nil {
 		log.Fatal("❌ Migration failed: ", err)
 	}
 
-	log.Println("✅ Migration completed: User table created")
+	// Refresh tokens used to be stored in plaintext on the user row
+	if database.DB.Migrator().HasColumn(&model.User{}, "refresh_token") {
+		if err := database.DB.Migrator().DropColumn(&model.User{}, "refresh_token"); err != nil {
+			log.Fatal("❌ Migration failed: ", err)
+		}
+	}
+
+	// Emails are unique regardless of case; users without an email are exempt
+	if err := database.DB.Exec(
+		"CREATE UNIQUE INDEX IF NOT EXISTS idx_users_email_lower ON users (LOWER(email)) WHERE email <> ''",
+	).Error; err != nil {
+		log.Fatal("❌ Migration failed: ", err)
+	}
+
+	createTrigramIndexes()
+
+	if err := seedRBAC(); err != nil {
+		log.Fatal("❌ Seeding roles failed: ", err)
+	}
+
+	log.Println("✅ Migration completed: tables created")
+}
+
+// createTrigramIndexes enables fuzzy user search. Databases where pg_trgm
+// cannot be installed still work: the search falls back to LIKE matching.
+func createTrigramIndexes() {
+	if err := database.DB.Exec("CREATE EXTENSION IF NOT EXISTS pg_trgm").Error; err != nil {
+		log.Println("⚠️ pg_trgm is not available, user search falls back to LIKE:", err)
+		return
+	}
+
+	for _, statement := range []string{
+		"CREATE INDEX IF NOT EXISTS idx_users_username_trgm ON users USING gin (username gin_trgm_ops)",
+		"CREATE INDEX IF NOT EXISTS idx_users_full_name_trgm ON users USING gin (full_name gin_trgm_ops)",
+	} {
+		if err := database.DB.Exec(statement).Error; err != nil {
+			log.Fatal("❌ Migration failed: ", err)
+		}
+	}
 }
@@ -0,0 +1,59 @@
+package migrations
+
+import (
+	"go-journey/src/database"
+	"go-journey/src/model"
+)
+
+// defaultRoles are created on first migration. Admin additionally receives
+// every default permission on each run, so newly added permissions reach it.
+var defaultRoles = map[string]string{
+	model.RoleGuest: "Read-only access to public resources",
+	model.RoleUser:  "Regular registered user",
+	model.RoleAdmin: "Full administrative access",
+}
+
+// defaultRolePermissions are granted to the other default roles only when
+// they are created, so that admins can revoke them for good. Every account
+// can read the user directory, which was public before permissions existed.
+var defaultRolePermissions = map[string][]string{
+	model.RoleGuest: {model.PermUsersRead},
+	model.RoleUser:  {model.PermUsersRead},
+}
+
+func seedRBAC() error {
+	permissions := make([]model.Permission, 0, len(model.DefaultPermissions))
+	byName := make(map[string]model.Permission, len(model.DefaultPermissions))
+	for name, description := range model.DefaultPermissions {
+		permission := model.Permission{Name: name, Description: description}
+		if err := database.DB.Where("name = ?", name).FirstOrCreate(&permission).Error; err != nil {
+			return err
+		}
+		permissions = append(permissions, permission)
+		byName[name] = permission
+	}
+
+	for name, description := range defaultRoles {
+		role := model.Role{Name: name, Description: description}
+		result := database.DB.Where("name = ?", name).FirstOrCreate(&role)
+		if result.Error != nil {
+			return result.Error
+		}
+
+		var grant []model.Permission
+		switch {
+		case name == model.RoleAdmin:
+			grant = permissions
+		case result.RowsAffected > 0:
+			for _, permission := range defaultRolePermissions[name] {
+				grant = append(grant, byName[permission])
+			}
+		}
+		if len(grant) > 0 {
+			if err := database.DB.Model(&role).Association("Permissions").Append(grant); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,118 @@
+package database
+
+import (
+	"errors"
+	"log"
+	"strconv"
+	"sync"
+	"time"
+
+	"go-journey/src/model"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Storage implements fiber.Storage on top of DB so that middleware state,
+// such as rate limiter counters, is shared by all instances.
+type Storage struct {
+	done chan struct{}
+	once sync.Once
+}
+
+// NewStorage returns a Storage that deletes expired entries every gcInterval
+func NewStorage(gcInterval time.Duration) *Storage {
+	s := &Storage{done: make(chan struct{})}
+	go s.gc(gcInterval)
+	return s
+}
+
+// Get returns nil, nil when the key does not exist or has expired
+func (s *Storage) Get(key string) ([]byte, error) {
+	if key == "" {
+		return nil, nil
+	}
+	var entry model.StorageEntry
+	err := DB.Where("key = ? AND (expires_at IS NULL OR expires_at > ?)", key, time.Now()).First(&entry).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return entry.Value, nil
+}
+
+// Set upserts a value; an exp of 0 means it never expires
+func (s *Storage) Set(key string, val []byte, exp time.Duration) error {
+	if key == "" || len(val) == 0 {
+		return nil
+	}
+	entry := model.StorageEntry{Key: key, Value: val}
+	if exp > 0 {
+		expiresAt := time.Now().Add(exp)
+		entry.ExpiresAt = &expiresAt
+	}
+	return DB.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "key"}},
+		DoUpdates: clause.AssignmentColumns([]string{"value", "expires_at"}),
+	}).Create(&entry).Error
+}
+
+// Increment adds one to the counter stored under key and returns the new
+// count and when the counter expires. An expired counter restarts at one
+// with a fresh exp. A single upsert keeps concurrent requests on any number
+// of instances from losing hits, which Get followed by Set cannot.
+func (s *Storage) Increment(key string, exp time.Duration) (int, time.Time, error) {
+	now := time.Now()
+	var counter struct {
+		Hits      string
+		ExpiresAt time.Time
+	}
+	err := DB.Raw(`
+		INSERT INTO fiber_storage (key, value, expires_at) VALUES (?, ?, ?)
+		ON CONFLICT (key) DO UPDATE SET
+			value = CASE WHEN fiber_storage.expires_at IS NULL OR fiber_storage.expires_at <= ? THEN EXCLUDED.value
+				ELSE convert_to((convert_from(fiber_storage.value, 'UTF8')::int + 1)::text, 'UTF8') END,
+			expires_at = CASE WHEN fiber_storage.expires_at IS NULL OR fiber_storage.expires_at <= ? THEN EXCLUDED.expires_at
+				ELSE fiber_storage.expires_at END
+		RETURNING convert_from(value, 'UTF8') AS hits, expires_at`,
+		key, []byte("1"), now.Add(exp), now, now).Scan(&counter).Error
+	if err != nil {
+		return 0, time.Time{}, err
+	}
+	hits, err := strconv.Atoi(counter.Hits)
+	return hits, counter.ExpiresAt, err
+}
+
+func (s *Storage) Delete(key string) error {
+	if key == "" {
+		return nil
+	}
+	return DB.Where("key = ?", key).Delete(&model.StorageEntry{}).Error
+}
+
+func (s *Storage) Reset() error {
+	return DB.Where("1 = 1").Delete(&model.StorageEntry{}).Error
+}
+
+// Close stops the garbage collector; the database connection is shared and stays open
+func (s *Storage) Close() error {
+	s.once.Do(func() { close(s.done) })
+	return nil
+}
+
+func (s *Storage) gc(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-s.done:
+			return
+		case <-ticker.C:
+			if err := DB.Where("expires_at <= ?", time.Now()).Delete(&model.StorageEntry{}).Error; err != nil {
+				log.Println("[Storage] gc failed:", err)
+			}
+		}
+	}
+}
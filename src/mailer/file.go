@@ -0,0 +1,28 @@
+package mailer
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"time"
+)
+
+// FileMailer writes every message as an .eml file into a directory. Useful
+// for local development.
+type FileMailer struct {
+	Dir     string
+	counter atomic.Uint64
+}
+
+func NewFileMailer(dir string) *FileMailer {
+	return &FileMailer{Dir: dir}
+}
+
+func (m *FileMailer) Send(msg Message) error {
+	if err := os.MkdirAll(m.Dir, 0o755); err != nil {
+		return err
+	}
+	name := fmt.Sprintf("%s-%d.eml", time.Now().Format("20060102T150405.000000000"), m.counter.Add(1))
+	return os.WriteFile(filepath.Join(m.Dir, name), render(os.Getenv("SMTP_FROM"), msg), 0o644)
+}
@@ -0,0 +1,50 @@
+package mailer
+
+import (
+	"log"
+	"os"
+)
+
+// Message is a plain text email
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Mailer sends email. Implementations: SMTPMailer, FileMailer and MemoryMailer.
+type Mailer interface {
+	Send(msg Message) error
+}
+
+// Default is the mailer used by the application
+var Default Mailer = NewMemoryMailer()
+
+// Init selects the mailer from MAIL_DRIVER (smtp, file or memory). When
+// MAIL_DRIVER is empty, SMTP is used if SMTP_HOST is set.
+func Init() {
+	driver := os.Getenv("MAIL_DRIVER")
+	if driver == "" && os.Getenv("SMTP_HOST") != "" {
+		driver = "smtp"
+	}
+
+	switch driver {
+	case "smtp":
+		Default = NewSMTPMailer()
+	case "file":
+		dir := os.Getenv("MAIL_FILE_DIR")
+		if dir == "" {
+			dir = "mail"
+		}
+		Default = NewFileMailer(dir)
+	default:
+		driver = "memory"
+		Default = NewMemoryMailer()
+	}
+	log.Printf("📧 Mail driver: %s", driver)
+}
+
+// Send delivers a message with the default mailer
+func Send(msg Message) error {
+	return Default.Send(msg)
+}
@@ -1,14 +1,41 @@
 package middleware
 
 import (
+	"go-journey/src/service"
 	"go-journey/src/utils"
+	"log"
+	"strings"
+	"time"
 
 	"github.com/gofiber/fiber/v2"
 )
 
+// Auth accepts either an access token or an API key, with or without the
+// "Bearer " prefix, and stores the caller in Locals. Without an Authorization
+// header the access token cookie of a browser session is used, and unsafe
+// methods must then pass the CSRF check. Access tokens issued to OpenID
+// Connect clients are rejected: they only grant access to ClientAuth routes.
 func Auth() fiber.Handler {
+	return authenticate(false)
+}
+
+// ClientAuth is Auth that also accepts the access tokens of OpenID Connect
+// clients, for the endpoints that serve relying parties such as userinfo.
+func ClientAuth() fiber.Handler {
+	return authenticate(true)
+}
+
+func authenticate(allowClientTokens bool) fiber.Handler {
 	return func(c *fiber.Ctx) error {
-		tokenStr := c.Get("Authorization")
+		tokenStr := strings.TrimSpace(c.Get("Authorization"))
+		if len(tokenStr) > 7 && strings.EqualFold(tokenStr[:7], "Bearer ") {
+			tokenStr = strings.TrimSpace(tokenStr[7:])
+		}
+
+		fromCookie := false
+		if tokenStr == "" {
+			tokenStr, fromCookie = c.Cookies(utils.AccessTokenCookie), true
+		}
 
 		if tokenStr == "" {
 			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
@@ -16,6 +43,10 @@ func Auth() fiber.Handler {
 			})
 		}
 
+		if !fromCookie && service.IsAPIKey(tokenStr) {
+			return apiKeyAuth(c, tokenStr)
+		}
+
 		token, claims, err := utils.ParseToken(tokenStr)
 		if err != nil || !token.Valid {
 			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
@@ -29,6 +60,14 @@ func Auth() fiber.Handler {
 			})
 		}
 
+		// Client tokens name the relying party in "aud"
+		audience, _ := claims.GetAudience()
+		if len(audience) > 0 && !allowClientTokens {
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+				"message": "token was issued to a client application",
+			})
+		}
+
 		sub, ok := claims["sub"].(string)
 		if !ok {
 			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
@@ -36,7 +75,108 @@ func Auth() fiber.Handler {
 			})
 		}
 
+		// Logout denylists the token; role changes, password changes and
+		// deletion bump the user's token version
+		if utils.AccessTokenRevoked(claims) {
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+				"message": "token revoked",
+			})
+		}
+
+		// Impersonation tokens name the admin in an RFC 8693 "act" claim
+		var actorID string
+		if act, ok := claims["act"].(map[string]interface{}); ok {
+			actorID, _ = act["sub"].(string)
+		}
+
+		jti, _ := claims["jti"].(string)
+		role, _ := claims["role"].(string)
+		sid, _ := claims["sid"].(string)
+
+		if fromCookie && !utils.SafeMethod(c.Method()) && !utils.ValidCSRF(c, sid) {
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+				"message": "invalid csrf token",
+			})
+		}
+
+		exp, _ := claims.GetExpirationTime()
+		authTime, hasAuthTime := claims["auth_time"].(float64)
+
 		c.Locals("userID", sub)
+		c.Locals("role", role)
+		c.Locals("authMethod", "token")
+		c.Locals("sessionID", sid)
+		c.Locals("jti", jti)
+		if exp != nil {
+			c.Locals("tokenExpiresAt", exp.Time)
+		}
+		if hasAuthTime {
+			c.Locals("authTime", time.Unix(int64(authTime), 0))
+		}
+		if actorID != "" {
+			c.Locals("actorID", actorID)
+			log.Printf("[Impersonation] %s as %s: %s %s", actorID, sub, c.Method(), c.Path())
+		}
+		return c.Next()
+	}
+}
+
+func apiKeyAuth(c *fiber.Ctx, credential string) error {
+	key, user, err := service.AuthenticateAPIKey(credential, c.IP())
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"message": "invalid api key",
+		})
+	}
+
+	c.Locals("userID", user.ID)
+	c.Locals("role", user.Role)
+	c.Locals("authMethod", "api_key")
+	c.Locals("apiKeyID", key.ID)
+	if len(key.Scopes) > 0 {
+		c.Locals("scopes", key.Scopes)
+	}
+	return c.Next()
+}
+
+// RequireSession rejects API key requests, for endpoints that manage the
+// user's own credentials and sessions.
+func RequireSession() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if method, _ := c.Locals("authMethod").(string); method != "token" {
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+				"message": "this endpoint requires an interactive login",
+			})
+		}
+		return c.Next()
+	}
+}
+
+// RequireRecentAuth only lets through access tokens whose user logged in or
+// confirmed their credentials at /auth/reauthenticate within maxAge. API keys,
+// impersonation tokens and tokens of OpenID Connect clients carry no
+// auth_time and are always rejected.
+func RequireRecentAuth(maxAge time.Duration) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if !utils.RecentlyAuthenticated(c, maxAge) {
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+				"message": "please confirm your password to continue",
+				"code":    "reauthentication_required",
+			})
+		}
+		return c.Next()
+	}
+}
+
+// RefuseImpersonation rejects impersonation tokens, for routes an admin must
+// not use on a user's behalf.
+func RefuseImpersonation() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if actorID, _ := c.Locals("actorID").(string); actorID != "" {
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+				"message": "not allowed while impersonating a user",
+			})
+		}
 		return c.Next()
 	}
 }
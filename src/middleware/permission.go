@@ -0,0 +1,53 @@
+package middleware
+
+import (
+	"go-journey/src/service"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// RequirePermission allows the request only if the caller's role grants
+// every one of the given permissions. Scoped API keys must also carry them.
+func RequirePermission(permissions ...string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		role, ok := c.Locals("role").(string)
+		if !ok {
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+				"message": "invalid role in context",
+			})
+		}
+
+		scopes, scoped := c.Locals("scopes").([]string)
+
+		for _, permission := range permissions {
+			if scoped && !containsString(scopes, permission) {
+				return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+					"message": "api key is missing scope " + permission,
+				})
+			}
+
+			allowed, err := service.RoleHasPermission(role, permission)
+			if err != nil {
+				return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+					"message": "failed to resolve permissions",
+				})
+			}
+			if !allowed {
+				return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+					"message": "missing permission " + permission,
+				})
+			}
+		}
+
+		return c.Next()
+	}
+}
+
+func containsString(values []string, s string) bool {
+	for _, v := range values {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,129 @@
+package middleware
+
+import (
+	"go-journey/src/database"
+	"go-journey/src/utils"
+	"log"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/middleware/limiter"
+)
+
+var (
+	rateLimitStorage     fiber.Storage
+	rateLimitStorageOnce sync.Once
+)
+
+// sharedStorage is created on first use, after the database is connected
+func sharedStorage() fiber.Storage {
+	rateLimitStorageOnce.Do(func() {
+		rateLimitStorage = database.NewStorage(time.Minute)
+	})
+	return rateLimitStorage
+}
+
+// RateLimit limits requests per client to max within window, with counters
+// kept in the database so the limit holds across replicas. The defaults can
+// be overridden with RATE_LIMIT_<NAME>="<max>/<window>", e.g. "5/1m"; a max
+// of 0 disables the limiter. Authenticated requests are counted per user,
+// anonymous ones per IP. Routes sharing a name share one budget.
+func RateLimit(name string, max int, window time.Duration) fiber.Handler {
+	envKey := "RATE_LIMIT_" + strings.ToUpper(name)
+	max, window = rateLimitFromEnv(envKey, max, window)
+	if max <= 0 {
+		return func(c *fiber.Ctx) error {
+			return c.Next()
+		}
+	}
+
+	return limiter.New(limiter.Config{
+		Max:               max,
+		Expiration:        window,
+		Storage:           sharedStorage(),
+		LimiterMiddleware: AtomicFixedWindow{},
+		KeyGenerator: func(c *fiber.Ctx) string {
+			if userID, ok := c.Locals("userID").(string); ok && userID != "" {
+				return "ratelimit:" + name + ":user:" + userID
+			}
+			return "ratelimit:" + name + ":ip:" + c.IP()
+		},
+		LimitReached: func(c *fiber.Ctx) error {
+			return c.Status(fiber.StatusTooManyRequests).JSON(fiber.Map{
+				"success": false,
+				"message": "Too many requests. Please try again later.",
+			})
+		},
+	})
+}
+
+// counterStorage is a fiber.Storage that can count hits atomically
+type counterStorage interface {
+	fiber.Storage
+	Increment(key string, exp time.Duration) (int, time.Time, error)
+}
+
+// AtomicFixedWindow is a limiter.LimiterHandler that works like
+// limiter.FixedWindow, except that it counts with a single increment in the
+// storage. limiter.FixedWindow reads, counts and writes back under a
+// per-process lock, so concurrent requests on other replicas would overwrite
+// each other's hits. Storages without Increment fall back to
+// limiter.FixedWindow. Skipping failed or successful requests is not supported.
+type AtomicFixedWindow struct{}
+
+func (AtomicFixedWindow) New(cfg limiter.Config) fiber.Handler {
+	storage, ok := cfg.Storage.(counterStorage)
+	if !ok {
+		return limiter.FixedWindow{}.New(cfg)
+	}
+
+	limit := strconv.Itoa(cfg.Max)
+	return func(c *fiber.Ctx) error {
+		if cfg.Next != nil && cfg.Next(c) {
+			return c.Next()
+		}
+
+		hits, resetAt, err := storage.Increment(cfg.KeyGenerator(c), cfg.Expiration)
+		if err != nil {
+			return utils.InternalError(c, err)
+		}
+
+		reset := strconv.Itoa(max(0, int(math.Ceil(time.Until(resetAt).Seconds()))))
+		c.Set("X-RateLimit-Limit", limit)
+		c.Set("X-RateLimit-Remaining", strconv.Itoa(max(0, cfg.Max-hits)))
+		c.Set("X-RateLimit-Reset", reset)
+		if hits > cfg.Max {
+			c.Set(fiber.HeaderRetryAfter, reset)
+			return cfg.LimitReached(c)
+		}
+		return c.Next()
+	}
+}
+
+func rateLimitFromEnv(key string, max int, window time.Duration) (int, time.Duration) {
+	v := os.Getenv(key)
+	if v == "" {
+		return max, window
+	}
+
+	parts := strings.SplitN(v, "/", 2)
+	n, err := strconv.Atoi(strings.TrimSpace(parts[0]))
+	if err != nil {
+		log.Printf("[RateLimit] ignoring invalid %s=%q", key, v)
+		return max, window
+	}
+	if len(parts) == 2 {
+		d, err := time.ParseDuration(strings.TrimSpace(parts[1]))
+		if err != nil || d <= 0 {
+			log.Printf("[RateLimit] ignoring invalid %s=%q", key, v)
+			return max, window
+		}
+		window = d
+	}
+	return n, window
+}
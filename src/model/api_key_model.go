@@ -0,0 +1,36 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// APIKeyPrefix starts every API key, e.g. "gjk_1a2b3c4d5e6f7a8b_<secret>"
+const APIKeyPrefix = "gjk"
+
+// APIKey is a personal access token for machine clients. Only the SHA-256
+// hash of the secret is stored; Prefix identifies the key in listings and logs.
+type APIKey struct {
+	ID         string     `gorm:"type:char(36);primaryKey" json:"id"`
+	UserID     string     `gorm:"type:char(36);index;not null" json:"user_id"`
+	Name       string     `gorm:"type:varchar(100);not null" json:"name"`
+	Prefix     string     `gorm:"type:varchar(16);uniqueIndex;not null" json:"prefix"`
+	KeyHash    string     `gorm:"type:char(64);not null" json:"-"`
+	Scopes     []string   `gorm:"serializer:json;type:text" json:"scopes"`
+	ExpiresAt  *time.Time `json:"expires_at"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+	LastUsedIP string     `gorm:"type:varchar(64)" json:"last_used_ip"`
+	RevokedAt  *time.Time `json:"revoked_at"`
+	CreatedAt  time.Time  `gorm:"autoCreateTime" json:"created_at"`
+}
+
+func (k *APIKey) BeforeCreate(tx *gorm.DB) (err error) {
+	k.ID = uuid.New().String()
+	return
+}
+
+func (APIKey) TableName() string {
+	return "api_keys"
+}
@@ -0,0 +1,14 @@
+package model
+
+import "time"
+
+// DeniedToken is a revoked access token kept until its natural expiry
+type DeniedToken struct {
+	JTI       string    `gorm:"type:char(36);primaryKey" json:"jti"`
+	ExpiresAt time.Time `gorm:"index;not null" json:"expires_at"`
+	CreatedAt time.Time `gorm:"autoCreateTime" json:"created_at"`
+}
+
+func (DeniedToken) TableName() string {
+	return "denied_tokens"
+}
@@ -0,0 +1,31 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Invitation lets someone register with a preset role. Only the SHA-256 hash
+// of the code is stored; when Email is set the code only works for it.
+type Invitation struct {
+	ID        string     `gorm:"type:char(36);primaryKey" json:"id"`
+	CodeHash  string     `gorm:"type:char(64);uniqueIndex;not null" json:"-"`
+	Email     string     `gorm:"type:varchar(255)" json:"email"`
+	Role      string     `gorm:"type:varchar(20);not null" json:"role"`
+	CreatedBy string     `gorm:"type:char(36);not null" json:"created_by"`
+	ExpiresAt time.Time  `gorm:"not null" json:"expires_at"`
+	UsedAt    *time.Time `json:"used_at"`
+	UsedBy    *string    `gorm:"type:char(36)" json:"used_by"`
+	CreatedAt time.Time  `gorm:"autoCreateTime" json:"created_at"`
+}
+
+func (i *Invitation) BeforeCreate(tx *gorm.DB) (err error) {
+	i.ID = uuid.New().String()
+	return
+}
+
+func (Invitation) TableName() string {
+	return "invitations"
+}
@@ -0,0 +1,29 @@
+package model
+
+import "time"
+
+// LoginThrottle counts failed logins from one IP address across all accounts
+type LoginThrottle struct {
+	IP           string     `gorm:"type:varchar(64);primaryKey" json:"ip"`
+	FailedCount  int        `gorm:"default:0;not null" json:"failed_count"`
+	LastFailedAt time.Time  `gorm:"index;not null" json:"last_failed_at"`
+	LockedUntil  *time.Time `json:"locked_until"`
+}
+
+func (LoginThrottle) TableName() string {
+	return "login_throttles"
+}
+
+// AccountThrottle counts failed logins to one account from one IP address.
+// Keying by both means guessing from elsewhere cannot lock the owner out.
+type AccountThrottle struct {
+	UserID       string     `gorm:"type:char(36);primaryKey" json:"user_id"`
+	IP           string     `gorm:"type:varchar(64);primaryKey" json:"ip"`
+	FailedCount  int        `gorm:"default:0;not null" json:"failed_count"`
+	LastFailedAt time.Time  `gorm:"index;not null" json:"last_failed_at"`
+	LockedUntil  *time.Time `json:"locked_until"`
+}
+
+func (AccountThrottle) TableName() string {
+	return "account_throttles"
+}
@@ -0,0 +1,45 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// OAuthClient is an application that signs users in through go-journey's
+// OpenID Connect provider. Public clients (e.g. SPAs) have no secret and
+// must use PKCE; only the SHA-256 hash of a confidential client's secret is stored.
+type OAuthClient struct {
+	ID           string    `gorm:"type:char(36);primaryKey" json:"id"`
+	ClientID     string    `gorm:"type:varchar(64);uniqueIndex;not null" json:"client_id"`
+	Name         string    `gorm:"type:varchar(100);not null" json:"name"`
+	SecretHash   string    `gorm:"type:char(64)" json:"-"`
+	RedirectURIs []string  `gorm:"serializer:json;type:text;not null" json:"redirect_uris"`
+	CreatedAt    time.Time `gorm:"autoCreateTime" json:"created_at"`
+	UpdatedAt    time.Time `gorm:"autoUpdateTime" json:"updated_at"`
+}
+
+func (c *OAuthClient) BeforeCreate(tx *gorm.DB) (err error) {
+	c.ID = uuid.New().String()
+	return
+}
+
+func (OAuthClient) TableName() string {
+	return "oauth_clients"
+}
+
+// Confidential reports whether the client authenticates with a secret
+func (c *OAuthClient) Confidential() bool {
+	return c.SecretHash != ""
+}
+
+// AllowsRedirectURI reports whether uri exactly matches a registered redirect URI
+func (c *OAuthClient) AllowsRedirectURI(uri string) bool {
+	for _, registered := range c.RedirectURIs {
+		if registered == uri {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,21 @@
+package model
+
+import "time"
+
+// OAuthAuthorizationCode is a single-use code issued by /oauth2/authorize and
+// redeemed at /oauth2/token. It is keyed by the hash of the code.
+type OAuthAuthorizationCode struct {
+	CodeHash      string    `gorm:"type:char(64);primaryKey" json:"-"`
+	ClientID      string    `gorm:"type:varchar(64);index;not null" json:"client_id"`
+	UserID        string    `gorm:"type:char(36);not null" json:"user_id"`
+	RedirectURI   string    `gorm:"type:text;not null" json:"redirect_uri"`
+	Scope         string    `gorm:"type:varchar(255);not null" json:"scope"`
+	Nonce         string    `gorm:"type:varchar(255)" json:"-"`
+	CodeChallenge string    `gorm:"type:varchar(128);not null" json:"-"`
+	ExpiresAt     time.Time `gorm:"index;not null" json:"expires_at"`
+	CreatedAt     time.Time `gorm:"autoCreateTime" json:"created_at"`
+}
+
+func (OAuthAuthorizationCode) TableName() string {
+	return "oauth_authorization_codes"
+}
@@ -0,0 +1,17 @@
+package model
+
+import "time"
+
+// OAuthState holds the PKCE verifier of a social login between the redirect
+// to the provider and the callback. It is keyed by the hash of the state.
+type OAuthState struct {
+	StateHash    string    `gorm:"type:char(64);primaryKey" json:"-"`
+	Provider     string    `gorm:"type:varchar(20);not null" json:"provider"`
+	CodeVerifier string    `gorm:"type:varchar(128);not null" json:"-"`
+	ExpiresAt    time.Time `gorm:"index;not null" json:"expires_at"`
+	CreatedAt    time.Time `gorm:"autoCreateTime" json:"created_at"`
+}
+
+func (OAuthState) TableName() string {
+	return "oauth_states"
+}
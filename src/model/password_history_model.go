@@ -0,0 +1,26 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// PasswordHistory keeps the hashes of a user's recent passwords so they
+// cannot be reused.
+type PasswordHistory struct {
+	ID           string    `gorm:"type:char(36);primaryKey" json:"id"`
+	UserID       string    `gorm:"type:char(36);index;not null" json:"user_id"`
+	PasswordHash string    `gorm:"type:varchar(255);not null" json:"-"`
+	CreatedAt    time.Time `gorm:"autoCreateTime;index" json:"created_at"`
+}
+
+func (h *PasswordHistory) BeforeCreate(tx *gorm.DB) (err error) {
+	h.ID = uuid.New().String()
+	return
+}
+
+func (PasswordHistory) TableName() string {
+	return "password_history"
+}
@@ -0,0 +1,52 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+const (
+	PermUsersRead          = "users:read"
+	PermUsersCreate        = "users:create"
+	PermUsersUpdate        = "users:update"
+	PermUsersDelete        = "users:delete"
+	PermUsersActivate      = "users:activate"
+	PermUsersUnlock        = "users:unlock"
+	PermUsersImpersonate   = "users:impersonate"
+	PermRolesManage        = "roles:manage"
+	PermOAuthClientsManage = "oauth_clients:manage"
+	PermInvitationsManage  = "invitations:manage"
+)
+
+// DefaultPermissions are seeded on migration and always granted to the admin role
+var DefaultPermissions = map[string]string{
+	PermUsersRead:          "List, search and view other users",
+	PermUsersCreate:        "Create users",
+	PermUsersUpdate:        "Update users, including their role and password",
+	PermUsersDelete:        "Delete users",
+	PermUsersActivate:      "Activate and deactivate users",
+	PermUsersUnlock:        "Unlock accounts and IP addresses locked after failed logins",
+	PermUsersImpersonate:   "Log in as another user for support purposes",
+	PermRolesManage:        "Manage roles and permissions",
+	PermOAuthClientsManage: "Register and remove OpenID Connect client applications",
+	PermInvitationsManage:  "Invite people to register with a preset role",
+}
+
+type Permission struct {
+	ID          string    `gorm:"type:char(36);primaryKey" json:"id"`
+	Name        string    `gorm:"type:varchar(100);uniqueIndex;not null" json:"name"`
+	Description string    `gorm:"type:varchar(255)" json:"description"`
+	CreatedAt   time.Time `gorm:"autoCreateTime" json:"created_at"`
+	UpdatedAt   time.Time `gorm:"autoUpdateTime" json:"updated_at"`
+}
+
+func (p *Permission) BeforeCreate(tx *gorm.DB) (err error) {
+	p.ID = uuid.New().String()
+	return
+}
+
+func (Permission) TableName() string {
+	return "permissions"
+}
@@ -0,0 +1,27 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// RecoveryCode is a hashed one-time code that can replace a TOTP code when
+// the user has lost their authenticator.
+type RecoveryCode struct {
+	ID        string     `gorm:"type:char(36);primaryKey" json:"id"`
+	UserID    string     `gorm:"type:char(36);index;not null" json:"user_id"`
+	CodeHash  string     `gorm:"type:char(64);not null" json:"-"`
+	UsedAt    *time.Time `json:"used_at"`
+	CreatedAt time.Time  `gorm:"autoCreateTime" json:"created_at"`
+}
+
+func (r *RecoveryCode) BeforeCreate(tx *gorm.DB) (err error) {
+	r.ID = uuid.New().String()
+	return
+}
+
+func (RecoveryCode) TableName() string {
+	return "recovery_codes"
+}
@@ -0,0 +1,29 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// RefreshToken is one link in a session's rotation chain. The session acts as
+// the token family: presenting a token that was already rotated revokes it.
+type RefreshToken struct {
+	ID        string     `gorm:"type:char(36);primaryKey" json:"id"`
+	SessionID string     `gorm:"type:char(36);index;not null" json:"session_id"`
+	UserID    string     `gorm:"type:char(36);index;not null" json:"user_id"`
+	TokenHash string     `gorm:"type:char(64);uniqueIndex;not null" json:"-"`
+	ExpiresAt time.Time  `gorm:"index" json:"expires_at"`
+	RotatedAt *time.Time `json:"rotated_at"`
+	CreatedAt time.Time  `gorm:"autoCreateTime" json:"created_at"`
+}
+
+func (t *RefreshToken) BeforeCreate(tx *gorm.DB) (err error) {
+	t.ID = uuid.New().String()
+	return
+}
+
+func (RefreshToken) TableName() string {
+	return "refresh_tokens"
+}
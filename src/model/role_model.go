@@ -0,0 +1,32 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+const (
+	RoleGuest = "guest"
+	RoleUser  = "user"
+	RoleAdmin = "admin"
+)
+
+type Role struct {
+	ID          string       `gorm:"type:char(36);primaryKey" json:"id"`
+	Name        string       `gorm:"type:varchar(20);uniqueIndex;not null" json:"name"`
+	Description string       `gorm:"type:varchar(255)" json:"description"`
+	Permissions []Permission `gorm:"many2many:role_permissions" json:"permissions"`
+	CreatedAt   time.Time    `gorm:"autoCreateTime" json:"created_at"`
+	UpdatedAt   time.Time    `gorm:"autoUpdateTime" json:"updated_at"`
+}
+
+func (r *Role) BeforeCreate(tx *gorm.DB) (err error) {
+	r.ID = uuid.New().String()
+	return
+}
+
+func (Role) TableName() string {
+	return "roles"
+}
@@ -0,0 +1,40 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+const (
+	SecurityEventRefreshTokenReuse = "refresh_token_reuse"
+	SecurityEventMFAEnabled        = "mfa_enabled"
+	SecurityEventMFADisabled       = "mfa_disabled"
+	SecurityEventRecoveryCodeUsed  = "recovery_code_used"
+	SecurityEventImpersonation     = "impersonation"
+	SecurityEventPasswordChanged   = "password_changed"
+	SecurityEventAccountDeleted    = "account_deleted"
+	SecurityEventEmailChanged      = "email_changed"
+)
+
+// SecurityEvent is an audit record of a security relevant incident
+type SecurityEvent struct {
+	ID        string    `gorm:"type:char(36);primaryKey" json:"id"`
+	UserID    string    `gorm:"type:char(36);index" json:"user_id"`
+	ActorID   string    `gorm:"type:char(36);index" json:"actor_id"`
+	Type      string    `gorm:"type:varchar(50);index;not null" json:"type"`
+	IP        string    `gorm:"type:varchar(64)" json:"ip"`
+	UserAgent string    `gorm:"type:varchar(255)" json:"user_agent"`
+	Detail    string    `gorm:"type:text" json:"detail"`
+	CreatedAt time.Time `gorm:"autoCreateTime" json:"created_at"`
+}
+
+func (e *SecurityEvent) BeforeCreate(tx *gorm.DB) (err error) {
+	e.ID = uuid.New().String()
+	return
+}
+
+func (SecurityEvent) TableName() string {
+	return "security_events"
+}
@@ -0,0 +1,42 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Session represents one logged-in device. Each session is a refresh token
+// family, so users can stay signed in on several devices. AuthenticatedAt is
+// when the user logged in; it is not set for sessions of OpenID Connect clients.
+type Session struct {
+	ID              string     `gorm:"type:char(36);primaryKey" json:"id"`
+	UserID          string     `gorm:"type:char(36);index;not null" json:"-"`
+	DeviceName      string     `gorm:"type:varchar(100)" json:"device_name"`
+	UserAgent       string     `gorm:"type:varchar(255)" json:"user_agent"`
+	IP              string     `gorm:"type:varchar(64)" json:"ip"`
+	ClientID        string     `gorm:"type:varchar(64)" json:"client_id"`
+	Scope           string     `gorm:"type:varchar(255)" json:"-"`
+	AccessJTI       string     `gorm:"type:char(36)" json:"-"`
+	AccessExpiresAt time.Time  `json:"-"`
+	AuthenticatedAt *time.Time `json:"authenticated_at"`
+	// ElevatedJTI identifies the last token issued by /auth/reauthenticate
+	ElevatedJTI       string     `gorm:"type:char(36)" json:"-"`
+	ElevatedExpiresAt time.Time  `json:"-"`
+	CreatedAt         time.Time  `gorm:"autoCreateTime" json:"created_at"`
+	LastUsedAt        time.Time  `json:"last_used_at"`
+	ExpiresAt         time.Time  `gorm:"index" json:"expires_at"`
+	RevokedAt         *time.Time `json:"-"`
+}
+
+func (s *Session) BeforeCreate(tx *gorm.DB) (err error) {
+	if s.ID == "" {
+		s.ID = uuid.New().String()
+	}
+	return
+}
+
+func (Session) TableName() string {
+	return "sessions"
+}
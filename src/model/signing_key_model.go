@@ -0,0 +1,24 @@
+package model
+
+import "time"
+
+const (
+	SigningKeyActive  = "active"
+	SigningKeyRetired = "retired"
+)
+
+// SigningKey is an asymmetric JWT signing key. Active keys sign new tokens,
+// retired keys are kept (and published) until every token they signed expired.
+type SigningKey struct {
+	Kid        string     `gorm:"type:varchar(64);primaryKey" json:"kid"`
+	Algorithm  string     `gorm:"type:varchar(10);not null" json:"alg"`
+	PrivateKey string     `gorm:"type:text;not null" json:"-"`
+	PublicKey  string     `gorm:"type:text;not null" json:"-"`
+	Status     string     `gorm:"type:varchar(10);index;not null" json:"status"`
+	CreatedAt  time.Time  `gorm:"autoCreateTime" json:"created_at"`
+	RetiredAt  *time.Time `json:"retired_at"`
+}
+
+func (SigningKey) TableName() string {
+	return "signing_keys"
+}
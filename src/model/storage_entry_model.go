@@ -0,0 +1,15 @@
+package model
+
+import "time"
+
+// StorageEntry is a key/value pair of the shared Fiber storage, used by the
+// rate limiters so that every replica sees the same counters.
+type StorageEntry struct {
+	Key       string     `gorm:"type:varchar(255);primaryKey"`
+	Value     []byte     `gorm:"type:bytea;not null"`
+	ExpiresAt *time.Time `gorm:"index"`
+}
+
+func (StorageEntry) TableName() string {
+	return "fiber_storage"
+}
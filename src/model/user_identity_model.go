@@ -0,0 +1,28 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// UserIdentity links a user to an account at an external OAuth provider
+type UserIdentity struct {
+	ID        string    `gorm:"type:char(36);primaryKey" json:"id"`
+	UserID    string    `gorm:"type:char(36);index;not null" json:"user_id"`
+	Provider  string    `gorm:"type:varchar(20);uniqueIndex:idx_identity_provider_subject;not null" json:"provider"`
+	Subject   string    `gorm:"type:varchar(255);uniqueIndex:idx_identity_provider_subject;not null" json:"subject"`
+	Email     string    `gorm:"type:varchar(255)" json:"email"`
+	CreatedAt time.Time `gorm:"autoCreateTime" json:"created_at"`
+	UpdatedAt time.Time `gorm:"autoUpdateTime" json:"updated_at"`
+}
+
+func (i *UserIdentity) BeforeCreate(tx *gorm.DB) (err error) {
+	i.ID = uuid.New().String()
+	return
+}
+
+func (UserIdentity) TableName() string {
+	return "user_identities"
+}
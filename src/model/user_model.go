@@ -7,19 +7,33 @@ import (
 	"gorm.io/gorm"
 )
 
+const (
+	UserStatusActive   = "active"
+	UserStatusDisabled = "disabled"
+	// UserStatusPending marks self-registered users awaiting admin approval
+	UserStatusPending = "pending"
+)
+
 type User struct {
-	ID            string         `gorm:"type:char(36);primaryKey" json:"id"`
-	Username      string         `gorm:"type:varchar(100);uniqueIndex;not null" json:"username"`
-	Password      string         `gorm:"type:varchar(255);not null" json:"-"`
-	FullName      string         `gorm:"type:varchar(150);not null" json:"full_name"`
-	Role          string         `gorm:"type:varchar(20);default:'guest';not null" json:"role"`
-	RegisterDate  time.Time      `gorm:"autoCreateTime" json:"register_date"`
-	EsignID       string         `gorm:"type:varchar(100)" json:"esign_id"`
-	EsignStatusID string         `gorm:"type:varchar(50)" json:"esign_status_id"`
-	RefreshToken  string         `gorm:"type:varchar(255)" json:"-"`
-	CreatedAt     time.Time      `gorm:"autoCreateTime" json:"created_at"`
-	UpdatedAt     time.Time      `gorm:"autoUpdateTime" json:"updated_at"`
-	DeletedAt     gorm.DeletedAt `gorm:"index" json:"-"`
+	ID                string         `gorm:"type:char(36);primaryKey" json:"id"`
+	Username          string         `gorm:"type:varchar(100);uniqueIndex;not null" json:"username"`
+	Email             string         `gorm:"type:varchar(255)" json:"email"`
+	EmailVerifiedAt   *time.Time     `json:"email_verified_at"`
+	Password          string         `gorm:"type:varchar(255);not null" json:"-"`
+	FullName          string         `gorm:"type:varchar(150);not null" json:"full_name"`
+	Role              string         `gorm:"type:varchar(20);default:'guest';not null" json:"role"`
+	Status            string         `gorm:"type:varchar(20);default:'active';not null" json:"status"`
+	RegisterDate      time.Time      `gorm:"autoCreateTime" json:"register_date"`
+	EsignID           string         `gorm:"type:varchar(100)" json:"esign_id"`
+	EsignStatusID     string         `gorm:"type:varchar(50)" json:"esign_status_id"`
+	TokenVersion      int            `gorm:"default:0;not null" json:"-"`
+	TOTPSecret        string         `gorm:"type:varchar(64)" json:"-"`
+	TOTPEnabledAt     *time.Time     `json:"totp_enabled_at"`
+	TOTPLastStep      int64          `gorm:"default:0;not null" json:"-"`
+	MagicLinkDisabled bool           `gorm:"default:false;not null" json:"magic_link_disabled"`
+	CreatedAt         time.Time      `gorm:"autoCreateTime" json:"created_at"`
+	UpdatedAt         time.Time      `gorm:"autoUpdateTime" json:"updated_at"`
+	DeletedAt         gorm.DeletedAt `gorm:"index" json:"-"`
 }
 
 func (u *User) BeforeCreate(tx *gorm.DB) (err error) {
@@ -0,0 +1,37 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+const (
+	TokenPurposePasswordReset     = "password_reset"
+	TokenPurposeEmailVerification = "email_verification"
+	TokenPurposeMagicLink         = "magic_link"
+)
+
+// UserToken is a single-use, expiring token sent to a user, e.g. a password
+// reset link. Only the SHA-256 hash of the token is stored. BindingHash ties
+// a magic link to the device that requested it.
+type UserToken struct {
+	ID          string     `gorm:"type:char(36);primaryKey" json:"id"`
+	UserID      string     `gorm:"type:char(36);index;not null" json:"user_id"`
+	Purpose     string     `gorm:"type:varchar(30);index;not null" json:"purpose"`
+	TokenHash   string     `gorm:"type:char(64);uniqueIndex;not null" json:"-"`
+	BindingHash string     `gorm:"type:char(64)" json:"-"`
+	ExpiresAt   time.Time  `gorm:"not null" json:"expires_at"`
+	UsedAt      *time.Time `json:"used_at"`
+	CreatedAt   time.Time  `gorm:"autoCreateTime" json:"created_at"`
+}
+
+func (t *UserToken) BeforeCreate(tx *gorm.DB) (err error) {
+	t.ID = uuid.New().String()
+	return
+}
+
+func (UserToken) TableName() string {
+	return "user_tokens"
+}
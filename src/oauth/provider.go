@@ -0,0 +1,231 @@
+package oauth
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+// Profile is the user information returned by a provider
+type Profile struct {
+	Subject       string
+	Email         string
+	EmailVerified bool
+	Name          string
+	Username      string
+}
+
+// Provider is an OAuth2 authorization server. The endpoints are configurable
+// so that tests can point them to a local fake server.
+type Provider struct {
+	Name         string
+	ClientID     string
+	ClientSecret string
+	RedirectURI  string
+	AuthURL      string
+	TokenURL     string
+	UserInfoURL  string
+	EmailsURL    string
+	Scopes       []string
+}
+
+var httpClient = &http.Client{Timeout: 10 * time.Second}
+
+// Get returns the configured provider by name ("google" or "github")
+func Get(name string) (*Provider, bool) {
+	var p *Provider
+	switch name {
+	case "google":
+		p = &Provider{
+			Name:        name,
+			AuthURL:     envOr("OAUTH_GOOGLE_AUTH_URL", "https://accounts.google.com/o/oauth2/v2/auth"),
+			TokenURL:    envOr("OAUTH_GOOGLE_TOKEN_URL", "https://oauth2.googleapis.com/token"),
+			UserInfoURL: envOr("OAUTH_GOOGLE_USERINFO_URL", "https://openidconnect.googleapis.com/v1/userinfo"),
+			Scopes:      []string{"openid", "email", "profile"},
+		}
+	case "github":
+		p = &Provider{
+			Name:        name,
+			AuthURL:     envOr("OAUTH_GITHUB_AUTH_URL", "https://github.com/login/oauth/authorize"),
+			TokenURL:    envOr("OAUTH_GITHUB_TOKEN_URL", "https://github.com/login/oauth/access_token"),
+			UserInfoURL: envOr("OAUTH_GITHUB_USERINFO_URL", "https://api.github.com/user"),
+			EmailsURL:   envOr("OAUTH_GITHUB_EMAILS_URL", "https://api.github.com/user/emails"),
+			Scopes:      []string{"read:user", "user:email"},
+		}
+	default:
+		return nil, false
+	}
+
+	prefix := "OAUTH_" + strings.ToUpper(name) + "_"
+	p.ClientID = os.Getenv(prefix + "CLIENT_ID")
+	p.ClientSecret = os.Getenv(prefix + "CLIENT_SECRET")
+	p.RedirectURI = os.Getenv(prefix + "REDIRECT_URI")
+	if p.ClientID == "" {
+		return nil, false
+	}
+	return p, true
+}
+
+// CodeChallenge derives the S256 PKCE challenge of a verifier
+func CodeChallenge(verifier string) string {
+	sum := sha256.Sum256([]byte(verifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+// AuthCodeURL builds the URL the browser is redirected to
+func (p *Provider) AuthCodeURL(state string, verifier string) string {
+	q := url.Values{
+		"response_type":         {"code"},
+		"client_id":             {p.ClientID},
+		"redirect_uri":          {p.RedirectURI},
+		"scope":                 {strings.Join(p.Scopes, " ")},
+		"state":                 {state},
+		"code_challenge":        {CodeChallenge(verifier)},
+		"code_challenge_method": {"S256"},
+	}
+	sep := "?"
+	if strings.Contains(p.AuthURL, "?") {
+		sep = "&"
+	}
+	return p.AuthURL + sep + q.Encode()
+}
+
+// Exchange trades an authorization code for a provider access token
+func (p *Provider) Exchange(ctx context.Context, code string, verifier string) (string, error) {
+	form := url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {code},
+		"redirect_uri":  {p.RedirectURI},
+		"client_id":     {p.ClientID},
+		"client_secret": {p.ClientSecret},
+		"code_verifier": {verifier},
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.TokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+
+	var body struct {
+		AccessToken string `json:"access_token"`
+		Error       string `json:"error"`
+		Description string `json:"error_description"`
+	}
+	if err := doJSON(req, &body); err != nil {
+		return "", err
+	}
+	if body.AccessToken == "" {
+		return "", fmt.Errorf("token exchange failed: %s %s", body.Error, body.Description)
+	}
+	return body.AccessToken, nil
+}
+
+// FetchProfile loads the user's profile with a provider access token
+func (p *Provider) FetchProfile(ctx context.Context, accessToken string) (Profile, error) {
+	if p.Name == "github" {
+		return p.fetchGitHubProfile(ctx, accessToken)
+	}
+
+	var info struct {
+		Sub           string `json:"sub"`
+		Email         string `json:"email"`
+		EmailVerified bool   `json:"email_verified"`
+		Name          string `json:"name"`
+	}
+	if err := p.getJSON(ctx, p.UserInfoURL, accessToken, &info); err != nil {
+		return Profile{}, err
+	}
+	if info.Sub == "" {
+		return Profile{}, errors.New("userinfo response has no subject")
+	}
+	return Profile{
+		Subject:       info.Sub,
+		Email:         info.Email,
+		EmailVerified: info.EmailVerified,
+		Name:          info.Name,
+		Username:      strings.Split(info.Email, "@")[0],
+	}, nil
+}
+
+func (p *Provider) fetchGitHubProfile(ctx context.Context, accessToken string) (Profile, error) {
+	var user struct {
+		ID    int64  `json:"id"`
+		Login string `json:"login"`
+		Name  string `json:"name"`
+	}
+	if err := p.getJSON(ctx, p.UserInfoURL, accessToken, &user); err != nil {
+		return Profile{}, err
+	}
+	if user.ID == 0 {
+		return Profile{}, errors.New("user response has no id")
+	}
+
+	profile := Profile{
+		Subject:  fmt.Sprint(user.ID),
+		Name:     user.Name,
+		Username: user.Login,
+	}
+	if profile.Name == "" {
+		profile.Name = user.Login
+	}
+
+	var emails []struct {
+		Email    string `json:"email"`
+		Primary  bool   `json:"primary"`
+		Verified bool   `json:"verified"`
+	}
+	if err := p.getJSON(ctx, p.EmailsURL, accessToken, &emails); err != nil {
+		return Profile{}, err
+	}
+	for _, e := range emails {
+		if e.Primary {
+			profile.Email = e.Email
+			profile.EmailVerified = e.Verified
+		}
+	}
+	return profile, nil
+}
+
+func (p *Provider) getJSON(ctx context.Context, endpoint string, accessToken string, out interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+accessToken)
+	req.Header.Set("Accept", "application/json")
+	return doJSON(req, out)
+}
+
+func doJSON(req *http.Request, out interface{}) error {
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("%s %s: status %d", req.Method, req.URL.Path, resp.StatusCode)
+	}
+	return json.Unmarshal(body, out)
+}
+
+func envOr(key string, def string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
+	}
+	return def
+}
@@ -0,0 +1,178 @@
+package password
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"os"
+	"strings"
+
+	"go-journey/src/utils"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Supported hashing algorithms
+const (
+	AlgorithmArgon2id = "argon2id"
+	AlgorithmBcrypt   = "bcrypt"
+)
+
+// ErrUnknownHash is returned for an encoded hash in an unsupported format
+var ErrUnknownHash = errors.New("unknown password hash format")
+
+// HashParams selects the algorithm and cost used for new hashes
+type HashParams struct {
+	Algorithm string
+	// Argon2id memory in KiB, iterations and parallelism
+	Memory  uint32
+	Time    uint32
+	Threads uint8
+	// BcryptCost is the bcrypt work factor
+	BcryptCost int
+}
+
+const (
+	argon2SaltLength = 16
+	argon2KeyLength  = 32
+)
+
+// HashParamsFromEnv reads the hashing parameters from PASSWORD_HASH_*
+// variables. Values outside the range the algorithms accept fall back to the
+// defaults, since argon2 panics on zero threads or iterations.
+func HashParamsFromEnv() HashParams {
+	algorithm := strings.ToLower(os.Getenv("PASSWORD_HASH_ALG"))
+	if algorithm != AlgorithmBcrypt {
+		algorithm = AlgorithmArgon2id
+	}
+	threads := intParam("PASSWORD_HASH_ARGON2_THREADS", 2, 1, math.MaxUint8)
+	return HashParams{
+		Algorithm:  algorithm,
+		Memory:     uint32(intParam("PASSWORD_HASH_ARGON2_MEMORY", 64*1024, 8*threads, math.MaxInt32)),
+		Time:       uint32(intParam("PASSWORD_HASH_ARGON2_TIME", 3, 1, math.MaxInt32)),
+		Threads:    uint8(threads),
+		BcryptCost: intParam("PASSWORD_HASH_BCRYPT_COST", bcrypt.DefaultCost, bcrypt.MinCost, bcrypt.MaxCost),
+	}
+}
+
+// intParam reads an integer variable, using def when it is outside [min, max]
+func intParam(key string, def int, min int, max int) int {
+	v := utils.IntFromEnv(key, def)
+	if v < min || v > max {
+		log.Printf("[Password] ignoring %s=%d, must be between %d and %d", key, v, min, max)
+		return def
+	}
+	return v
+}
+
+// Hash encodes a password with the configured parameters. Argon2id hashes use
+// the PHC string format, e.g. "$argon2id$v=19$m=65536,t=3,p=2$<salt>$<hash>";
+// bcrypt hashes keep their native "$2a$" format.
+func Hash(password string) (string, error) {
+	return HashParamsFromEnv().Hash(password)
+}
+
+func (p HashParams) Hash(password string) (string, error) {
+	if p.Algorithm == AlgorithmBcrypt {
+		hash, err := bcrypt.GenerateFromPassword([]byte(password), p.BcryptCost)
+		return string(hash), err
+	}
+
+	salt := make([]byte, argon2SaltLength)
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
+	}
+	key := argon2.IDKey([]byte(password), salt, p.Time, p.Memory, p.Threads, argon2KeyLength)
+	return fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s",
+		argon2.Version, p.Memory, p.Time, p.Threads,
+		base64.RawStdEncoding.EncodeToString(salt),
+		base64.RawStdEncoding.EncodeToString(key),
+	), nil
+}
+
+// Verify reports whether password matches the encoded hash. needsRehash is
+// true when the hash should be replaced because it uses another algorithm or
+// outdated parameters.
+func Verify(password string, encoded string) (ok bool, needsRehash bool, err error) {
+	return HashParamsFromEnv().Verify(password, encoded)
+}
+
+func (p HashParams) Verify(password string, encoded string) (bool, bool, error) {
+	switch {
+	case strings.HasPrefix(encoded, "$argon2id$"):
+		h, err := decodeArgon2id(encoded)
+		if err != nil {
+			return false, false, err
+		}
+		key := argon2.IDKey([]byte(password), h.salt, h.time, h.memory, h.threads, uint32(len(h.key)))
+		if subtle.ConstantTimeCompare(key, h.key) != 1 {
+			return false, false, nil
+		}
+		outdated := p.Algorithm != AlgorithmArgon2id ||
+			h.memory != p.Memory || h.time != p.Time || h.threads != p.Threads ||
+			len(h.salt) != argon2SaltLength || len(h.key) != argon2KeyLength
+		return true, outdated, nil
+
+	case strings.HasPrefix(encoded, "$2a$"), strings.HasPrefix(encoded, "$2b$"), strings.HasPrefix(encoded, "$2y$"):
+		err := bcrypt.CompareHashAndPassword([]byte(encoded), []byte(password))
+		if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+			return false, false, nil
+		}
+		if err != nil {
+			return false, false, err
+		}
+		cost, err := bcrypt.Cost([]byte(encoded))
+		if err != nil {
+			return false, false, err
+		}
+		return true, p.Algorithm != AlgorithmBcrypt || cost != p.BcryptCost, nil
+	}
+	return false, false, ErrUnknownHash
+}
+
+// Matches reports whether password matches the encoded hash, treating
+// unreadable hashes as a mismatch.
+func Matches(password string, encoded string) bool {
+	ok, _, err := Verify(password, encoded)
+	return err == nil && ok
+}
+
+type argon2idHash struct {
+	memory  uint32
+	time    uint32
+	threads uint8
+	salt    []byte
+	key     []byte
+}
+
+func decodeArgon2id(encoded string) (argon2idHash, error) {
+	// "", "argon2id", "v=19", "m=...,t=...,p=...", salt, key
+	parts := strings.Split(encoded, "$")
+	if len(parts) != 6 {
+		return argon2idHash{}, ErrUnknownHash
+	}
+
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil || version != argon2.Version {
+		return argon2idHash{}, ErrUnknownHash
+	}
+
+	var h argon2idHash
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &h.memory, &h.time, &h.threads); err != nil || h.time == 0 || h.threads == 0 {
+		return argon2idHash{}, ErrUnknownHash
+	}
+
+	var err error
+	if h.salt, err = base64.RawStdEncoding.DecodeString(parts[4]); err != nil {
+		return argon2idHash{}, ErrUnknownHash
+	}
+	if h.key, err = base64.RawStdEncoding.DecodeString(parts[5]); err != nil || len(h.key) == 0 {
+		return argon2idHash{}, ErrUnknownHash
+	}
+	return h, nil
+}
@@ -0,0 +1,148 @@
+package password
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"go-journey/src/utils"
+)
+
+// PolicyError explains why a candidate password was rejected. Its message is
+// safe to show to the user.
+type PolicyError struct {
+	Message string
+}
+
+func (e *PolicyError) Error() string {
+	return e.Message
+}
+
+// Policy describes the rules every new password must satisfy
+type Policy struct {
+	MinLength  int
+	MaxLength  int
+	MinClasses int
+	// HistorySize is how many previous passwords may not be reused
+	HistorySize int
+	// BreachedDir holds breached password hashes in the k-anonymity range
+	// format: one file per 5 character SHA-1 prefix, e.g. "5BAA6.txt",
+	// with "SUFFIX:COUNT" lines. Empty disables the check.
+	BreachedDir string
+}
+
+// PolicyFromEnv reads the policy from PASSWORD_* environment variables
+func PolicyFromEnv() Policy {
+	dir, ok := os.LookupEnv("PASSWORD_BREACHED_DIR")
+	if !ok {
+		dir = "data/breached-passwords"
+	}
+	return Policy{
+		MinLength:   utils.IntFromEnv("PASSWORD_MIN_LENGTH", 8),
+		MaxLength:   utils.IntFromEnv("PASSWORD_MAX_LENGTH", 72),
+		MinClasses:  utils.IntFromEnv("PASSWORD_MIN_CLASSES", 3),
+		HistorySize: utils.IntFromEnv("PASSWORD_HISTORY", 5),
+		BreachedDir: dir,
+	}
+}
+
+// Check validates a candidate against the policy. identifiers are values the
+// password must not resemble, such as the username and email address.
+func (p Policy) Check(candidate string, identifiers ...string) error {
+	length := utf8.RuneCountInString(candidate)
+	if length < p.MinLength {
+		return &PolicyError{Message: fmt.Sprintf("Password must be at least %d characters", p.MinLength)}
+	}
+	if p.MaxLength > 0 && len(candidate) > p.MaxLength {
+		return &PolicyError{Message: fmt.Sprintf("Password must be at most %d bytes", p.MaxLength)}
+	}
+
+	if classes := characterClasses(candidate); classes < p.MinClasses {
+		return &PolicyError{Message: fmt.Sprintf(
+			"Password must contain at least %d of: lowercase letters, uppercase letters, digits and symbols", p.MinClasses)}
+	}
+
+	lower := strings.ToLower(candidate)
+	for _, id := range identifiers {
+		id = strings.ToLower(strings.TrimSpace(id))
+		if at := strings.Index(id, "@"); at >= 0 {
+			id = id[:at]
+		}
+		if len(id) < 3 {
+			continue
+		}
+		if strings.Contains(lower, id) || strings.Contains(id, lower) {
+			return &PolicyError{Message: "Password must not contain your username or email"}
+		}
+	}
+
+	breached, err := p.IsBreached(candidate)
+	if err != nil {
+		// A broken list must not block every password change
+		log.Println("[PasswordPolicy] breached password lookup failed:", err)
+	}
+	if breached {
+		return &PolicyError{Message: "This password has appeared in a data breach, please choose another one"}
+	}
+	return nil
+}
+
+// IsBreached reports whether the candidate is in the breached password list
+func (p Policy) IsBreached(candidate string) (bool, error) {
+	if p.BreachedDir == "" {
+		return false, nil
+	}
+
+	sum := sha1.Sum([]byte(candidate))
+	hash := strings.ToUpper(hex.EncodeToString(sum[:]))
+	prefix, suffix := hash[:5], hash[5:]
+
+	f, err := os.Open(filepath.Join(p.BreachedDir, prefix+".txt"))
+	if errors.Is(err, os.ErrNotExist) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if s, _, _ := strings.Cut(line, ":"); strings.EqualFold(s, suffix) {
+			return true, nil
+		}
+	}
+	return false, scanner.Err()
+}
+
+func characterClasses(s string) int {
+	var lower, upper, digit, other bool
+	for _, r := range s {
+		switch {
+		case unicode.IsLower(r):
+			lower = true
+		case unicode.IsUpper(r):
+			upper = true
+		case unicode.IsDigit(r):
+			digit = true
+		default:
+			other = true
+		}
+	}
+	n := 0
+	for _, ok := range []bool{lower, upper, digit, other} {
+		if ok {
+			n++
+		}
+	}
+	return n
+}
@@ -1,11 +1,102 @@
 package res
 
+import (
+	"time"
+
+	"go-journey/src/model"
+)
+
+// UserResponse is a user as returned by the user endpoints. It leaves out
+// credentials and security state such as two-factor details.
+type UserResponse struct {
+	ID              string     `json:"id"`
+	Username        string     `json:"username"`
+	Email           string     `json:"email"`
+	EmailVerifiedAt *time.Time `json:"email_verified_at"`
+	FullName        string     `json:"full_name"`
+	Role            string     `json:"role"`
+	Status          string     `json:"status"`
+	RegisterDate    time.Time  `json:"register_date"`
+	EsignID         string     `json:"esign_id"`
+	EsignStatusID   string     `json:"esign_status_id"`
+	CreatedAt       time.Time  `json:"created_at"`
+	UpdatedAt       time.Time  `json:"updated_at"`
+	// Lockout is only included for callers who may unlock accounts
+	Lockout *UserLockout `json:"lockout,omitempty"`
+}
+
+// UserLockout summarises the failed logins to an account over all the IP
+// addresses they came from
+type UserLockout struct {
+	FailedLogins int        `json:"failed_logins"`
+	LastFailedAt *time.Time `json:"last_failed_at"`
+	LockedUntil  *time.Time `json:"locked_until"`
+}
+
+// NewUserResponse maps a user to its response
+func NewUserResponse(user *model.User) UserResponse {
+	return UserResponse{
+		ID:              user.ID,
+		Username:        user.Username,
+		Email:           user.Email,
+		EmailVerifiedAt: user.EmailVerifiedAt,
+		FullName:        user.FullName,
+		Role:            user.Role,
+		Status:          user.Status,
+		RegisterDate:    user.RegisterDate,
+		EsignID:         user.EsignID,
+		EsignStatusID:   user.EsignStatusID,
+		CreatedAt:       user.CreatedAt,
+		UpdatedAt:       user.UpdatedAt,
+	}
+}
+
+// NewUserLockout summarises the failure counters of one account. LockedUntil
+// is the latest lockout still in force at now, if any.
+func NewUserLockout(throttles []model.AccountThrottle, now time.Time) *UserLockout {
+	lockout := &UserLockout{}
+	for i := range throttles {
+		t := &throttles[i]
+		lockout.FailedLogins += t.FailedCount
+		if lockout.LastFailedAt == nil || t.LastFailedAt.After(*lockout.LastFailedAt) {
+			lockout.LastFailedAt = &t.LastFailedAt
+		}
+		if t.LockedUntil != nil && t.LockedUntil.After(now) &&
+			(lockout.LockedUntil == nil || t.LockedUntil.After(*lockout.LockedUntil)) {
+			lockout.LockedUntil = t.LockedUntil
+		}
+	}
+	return lockout
+}
+
+// NewUserResponses maps a list of users to their responses
+func NewUserResponses(users []model.User) []UserResponse {
+	responses := make([]UserResponse, len(users))
+	for i := range users {
+		responses[i] = NewUserResponse(&users[i])
+	}
+	return responses
+}
+
 type Response struct {
 	Status  string      `json:"status"`
 	Success bool        `json:"success"`
 	Message string      `json:"message"`
 	Data    interface{} `json:"data,omitempty"`
+	Meta    *Pagination `json:"meta,omitempty"`
 	Error   string      `json:"error,omitempty"`
+	Code    string      `json:"code,omitempty"`
+}
+
+// Pagination describes one page of a list. Page, Total and TotalPages are
+// only set for page based pagination; NextCursor is set while HasMore.
+type Pagination struct {
+	Page       int    `json:"page,omitempty"`
+	PerPage    int    `json:"per_page"`
+	Total      *int64 `json:"total,omitempty"`
+	TotalPages *int   `json:"total_pages,omitempty"`
+	HasMore    bool   `json:"has_more"`
+	NextCursor string `json:"next_cursor,omitempty"`
 }
 
 // SuccessResponse untuk response sukses
@@ -18,6 +109,17 @@ func SuccessResponse(message string, data interface{}) Response {
 	}
 }
 
+// PaginatedResponse untuk response sukses berisi satu halaman data
+func PaginatedResponse(message string, data interface{}, meta Pagination) Response {
+	return Response{
+		Status:  "success",
+		Success: true,
+		Message: message,
+		Data:    data,
+		Meta:    &meta,
+	}
+}
+
 // ErrorResponse untuk response error
 func ErrorResponse(message string, err error) Response {
 	var errMsg string
@@ -31,3 +133,13 @@ func ErrorResponse(message string, err error) Response {
 		Error:   errMsg,
 	}
 }
+
+// ErrorCodeResponse untuk response error dengan kode error yang bisa dibaca client
+func ErrorCodeResponse(code string, message string) Response {
+	return Response{
+		Status:  "error",
+		Success: false,
+		Message: message,
+		Code:    code,
+	}
+}
@@ -6,43 +6,43 @@ import (
 	"time"
 
 	"github.com/gofiber/fiber/v2"
-	"github.com/gofiber/fiber/v2/middleware/limiter"
 )
 
 func AuthRoutes(app *fiber.App) {
 	auth := app.Group("/auth")
 
 	// 🔓 Public routes
-	auth.Post("/register", controller.Register)
+	auth.Post("/register", middleware.RateLimit("register", 10, time.Hour), controller.Register)
 
-	// Login with rate limiter
-	auth.Post("/login",
-		limiter.New(limiter.Config{
-			Max:        5,               // max 5 login attempts
-			Expiration: 1 * time.Minute, // within 1 minute
-			KeyGenerator: func(c *fiber.Ctx) string {
-				var body struct {
-					Username string `json:"username"`
-				}
-				_ = c.BodyParser(&body)
-				if body.Username != "" {
-					return body.Username
-				}
-				return c.IP()
-			},
-			LimitReached: func(c *fiber.Ctx) error {
-				return c.Status(fiber.StatusTooManyRequests).JSON(fiber.Map{
-					"success": false,
-					"message": "Too many login attempts for this account. Please try again later.",
-				})
-			},
-		}),
-		controller.Login,
-	)
-
-	auth.Post("/refresh", controller.Refresh)
+	// Failed logins are additionally locked out per account and per IP by the controller
+	loginLimit := middleware.RateLimit("login", 20, time.Minute)
+	auth.Post("/login", loginLimit, controller.Login)
+	auth.Post("/login/mfa", loginLimit, controller.LoginMFA)
+	auth.Post("/magic-link", middleware.RateLimit("magic_link", 10, time.Hour), controller.RequestMagicLink)
+	auth.Post("/magic-link/verify", loginLimit, controller.VerifyMagicLink)
+	auth.Post("/refresh", middleware.RateLimit("refresh", 60, time.Minute), controller.Refresh)
+	// Emails are additionally limited per address by the services
+	auth.Post("/forgot-password", middleware.RateLimit("forgot_password", 10, time.Hour), controller.ForgotPassword)
+	auth.Post("/resend-verification", middleware.RateLimit("resend_verification", 10, time.Hour), controller.ResendVerification)
+	tokenLimit := middleware.RateLimit("email_token", 30, time.Minute)
+	auth.Post("/reset-password", tokenLimit, controller.ResetPassword)
+	auth.Post("/verify-email", tokenLimit, controller.VerifyEmail)
+	auth.Get("/oauth/:provider/login", controller.OAuthLogin)
+	auth.Get("/oauth/:provider/callback", controller.OAuthCallback)
 
 	// 🔒 Protected routes
-	auth.Use(middleware.Auth())
+	auth.Use(middleware.Auth(), middleware.RequireSession(), middleware.RefuseImpersonation())
+	auth.Post("/reauthenticate", loginLimit, controller.Reauthenticate)
 	auth.Post("/logout", controller.Logout)
+	auth.Post("/logout-all", controller.LogoutAll)
+	auth.Get("/sessions", controller.GetSessions)
+	auth.Delete("/sessions/:id", controller.RevokeSession)
+	auth.Post("/mfa/totp/setup", controller.SetupTOTP)
+	auth.Post("/mfa/totp/confirm", controller.ConfirmTOTP)
+	auth.Post("/mfa/totp/disable", controller.DisableTOTP)
+	auth.Post("/mfa/recovery-codes", controller.RegenerateRecoveryCodes)
+	auth.Put("/magic-link/settings", controller.UpdateMagicLinkSettings)
+	auth.Get("/api-keys", controller.GetAPIKeys)
+	auth.Post("/api-keys", middleware.RequireRecentAuth(5*time.Minute), controller.CreateAPIKey)
+	auth.Delete("/api-keys/:id", controller.RevokeAPIKey)
 }
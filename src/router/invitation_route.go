@@ -0,0 +1,18 @@
+package router
+
+import (
+	"go-journey/src/controller"
+	"go-journey/src/middleware"
+	"go-journey/src/model"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func InvitationRoutes(app *fiber.App) {
+	// 🔐 Invitation management routes
+	invitations := app.Group("/invitations", middleware.Auth(), middleware.RefuseImpersonation(), middleware.RequirePermission(model.PermInvitationsManage))
+	invitations.Get("/", controller.GetInvitations)
+	invitations.Post("/", middleware.RequireRecentAuth(5*time.Minute), controller.CreateInvitation)
+	invitations.Delete("/:id", controller.RevokeInvitation)
+}
@@ -0,0 +1,16 @@
+package router
+
+import (
+	"go-journey/src/controller"
+	"go-journey/src/middleware"
+	"go-journey/src/model"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func LockoutRoutes(app *fiber.App) {
+	// 🔐 IP lockout management routes
+	lockouts := app.Group("/lockouts", middleware.Auth(), middleware.RefuseImpersonation(), middleware.RequirePermission(model.PermUsersUnlock))
+	lockouts.Get("/ips", controller.GetLockedIPs)
+	lockouts.Delete("/ips/:ip", controller.UnlockIP)
+}
@@ -0,0 +1,34 @@
+package router
+
+import (
+	"go-journey/src/controller"
+	"go-journey/src/middleware"
+	"go-journey/src/model"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func OIDCRoutes(app *fiber.App) {
+	oauth2 := app.Group("/oauth2")
+
+	// 🔓 Public routes
+	oauth2.Get("/authorize", controller.Authorize)
+	tokenLimit := middleware.RateLimit("token", 60, time.Minute)
+	oauth2.Post("/token", tokenLimit, controller.Token)
+	oauth2.Post("/revoke", tokenLimit, controller.Revoke)
+	// Resource servers introspect on every request, so this one is not rate limited
+	oauth2.Post("/introspect", controller.Introspect)
+
+	// 🔒 Protected routes
+	oauth2.Post("/authorize", middleware.Auth(), middleware.RequireSession(), middleware.RefuseImpersonation(), controller.ApproveAuthorization)
+	oauth2.Get("/userinfo", middleware.ClientAuth(), controller.UserInfo)
+	oauth2.Post("/userinfo", middleware.ClientAuth(), controller.UserInfo)
+
+	// 🔐 Client management routes
+	clients := oauth2.Group("/clients", middleware.Auth(), middleware.RefuseImpersonation(), middleware.RequirePermission(model.PermOAuthClientsManage))
+	clients.Get("/", controller.GetOAuthClients)
+	recentAuth := middleware.RequireRecentAuth(5 * time.Minute)
+	clients.Post("/", recentAuth, controller.CreateOAuthClient)
+	clients.Delete("/:client_id", recentAuth, controller.DeleteOAuthClient)
+}
@@ -0,0 +1,28 @@
+package router
+
+import (
+	"go-journey/src/controller"
+	"go-journey/src/middleware"
+	"go-journey/src/model"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func RoleRoutes(app *fiber.App) {
+	recentAuth := middleware.RequireRecentAuth(5 * time.Minute)
+
+	// 🔐 Role management routes
+	roles := app.Group("/roles", middleware.Auth(), middleware.RefuseImpersonation(), middleware.RequirePermission(model.PermRolesManage))
+	roles.Get("/", controller.GetRoles)
+	roles.Get("/:id", controller.GetRole)
+	roles.Post("/", recentAuth, controller.CreateRole)
+	roles.Put("/:id", recentAuth, controller.UpdateRole)
+	roles.Put("/:id/permissions", recentAuth, controller.SetRolePermissions)
+	roles.Delete("/:id", recentAuth, controller.DeleteRole)
+
+	permissions := app.Group("/permissions", middleware.Auth(), middleware.RefuseImpersonation(), middleware.RequirePermission(model.PermRolesManage))
+	permissions.Get("/", controller.GetPermissions)
+	permissions.Post("/", recentAuth, controller.CreatePermission)
+	permissions.Delete("/:id", recentAuth, controller.DeletePermission)
+}
@@ -3,6 +3,8 @@ package router
 import (
 	"go-journey/src/controller"
 	"go-journey/src/middleware"
+	"go-journey/src/model"
+	"time"
 
 	"github.com/gofiber/fiber/v2"
 )
@@ -10,16 +12,32 @@ import (
 func UserRoutes(app *fiber.App) {
 	user := app.Group("/users")
 
-	// 🔓 Public routes
-	user.Get("/", controller.GetUsers)
-	user.Get("/:id", controller.GetUser)
+	// 👤 Self-service routes, registered before /:id so "me" is not taken for an ID
+	auth, session, notImpersonating := middleware.Auth(), middleware.RequireSession(), middleware.RefuseImpersonation()
+	// Sensitive operations need a login or /auth/reauthenticate within the last minutes
+	recentAuth := middleware.RequireRecentAuth(5 * time.Minute)
+	user.Get("/me", auth, controller.GetMe)
+	user.Patch("/me", auth, session, notImpersonating, controller.UpdateMe)
+	user.Post("/me/password", auth, session, notImpersonating, controller.ChangeMyPassword)
+	user.Delete("/me", auth, session, notImpersonating, recentAuth, controller.DeleteMe)
+
+	// 🔎 Fuzzy search for support tools, also registered before /:id
+	user.Get("/search", auth, notImpersonating, middleware.RequirePermission(model.PermUsersRead),
+		middleware.RateLimit("user_search", 60, time.Minute), controller.SearchUsers)
+
+	// 👀 Directory routes, for staff with users:read
+	user.Get("/", auth, middleware.RequirePermission(model.PermUsersRead), controller.GetUsers)
+	user.Get("/:id", auth, middleware.RequirePermission(model.PermUsersRead), controller.GetUser)
 
 	// 🔒 Protected routes
-	protected := user.Group("/", middleware.Auth())
+	protected := user.Group("/", middleware.Auth(), middleware.RefuseImpersonation(), middleware.RateLimit("admin", 60, time.Minute))
 
-	// 🔐 Admin-only routes
-	admin := protected.Group("/", middleware.RoleMiddleware("admin"))
-	admin.Post("/", controller.CreateUser)
-	admin.Put("/:id", controller.UpdateUser)
-	admin.Delete("/:id", controller.DeleteUser)
+	// 🔐 Permission-gated routes
+	protected.Post("/", middleware.RequirePermission(model.PermUsersCreate), recentAuth, controller.CreateUser)
+	protected.Put("/:id", middleware.RequirePermission(model.PermUsersUpdate), recentAuth, controller.UpdateUser)
+	protected.Delete("/:id", middleware.RequirePermission(model.PermUsersDelete), recentAuth, controller.DeleteUser)
+	protected.Post("/:id/deactivate", middleware.RequirePermission(model.PermUsersActivate), controller.DeactivateUser)
+	protected.Post("/:id/activate", middleware.RequirePermission(model.PermUsersActivate), controller.ActivateUser)
+	protected.Post("/:id/unlock", middleware.RequirePermission(model.PermUsersUnlock), controller.UnlockUser)
+	protected.Post("/:id/impersonate", middleware.RequireSession(), middleware.RequirePermission(model.PermUsersImpersonate), recentAuth, controller.ImpersonateUser)
 }
@@ -0,0 +1,15 @@
+package router
+
+import (
+	"go-journey/src/controller"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func WellKnownRoutes(app *fiber.App) {
+	wellKnown := app.Group("/.well-known")
+
+	// 🔓 Public routes
+	wellKnown.Get("/jwks.json", controller.JWKS)
+	wellKnown.Get("/openid-configuration", controller.OpenIDConfiguration)
+}
@@ -0,0 +1,125 @@
+package service
+
+import (
+	"errors"
+	"go-journey/src/database"
+	"go-journey/src/model"
+	"go-journey/src/password"
+	"log"
+)
+
+var (
+	// ErrWrongPassword is returned when the current password does not match
+	ErrWrongPassword = errors.New("current password is incorrect")
+	// ErrUsernameTaken is returned when another user already has the username
+	ErrUsernameTaken = errors.New("username already used")
+	// ErrEmailTaken is returned when another user already has the email
+	ErrEmailTaken = errors.New("email already used")
+)
+
+// ProfileUpdate holds the fields users may change on their own account.
+// Empty fields are left unchanged.
+type ProfileUpdate struct {
+	Username string
+	Email    string
+	FullName string
+}
+
+// UpdateProfile applies a self-service profile update. It reports whether the
+// email changed, in which case the address must be verified again and the
+// previous address is told about the change.
+func UpdateProfile(user *model.User, update ProfileUpdate, meta SessionMeta) (bool, error) {
+	if update.Username != "" && update.Username != user.Username {
+		var count int64
+		if err := database.DB.Model(&model.User{}).
+			Where("username = ? AND id <> ?", update.Username, user.ID).
+			Count(&count).Error; err != nil {
+			return false, err
+		}
+		if count > 0 {
+			return false, ErrUsernameTaken
+		}
+		user.Username = update.Username
+	}
+	if update.FullName != "" {
+		user.FullName = update.FullName
+	}
+
+	previousEmail := user.Email
+	emailChanged := update.Email != "" && NormalizeEmail(update.Email) != user.Email
+	if emailChanged {
+		if EmailTaken(update.Email, user.ID) {
+			return false, ErrEmailTaken
+		}
+		user.Email = NormalizeEmail(update.Email)
+		user.EmailVerifiedAt = nil
+	}
+
+	err := database.DB.Model(user).
+		Select("username", "full_name", "email", "email_verified_at").
+		Updates(user).Error
+	if err != nil || !emailChanged {
+		return emailChanged, err
+	}
+
+	// Links sent to the previous address must not verify the new one
+	if err := RevokeUserTokens(user.ID, model.TokenPurposeEmailVerification); err != nil {
+		return true, err
+	}
+	_ = RecordSecurityEvent(user.ID, model.SecurityEventEmailChanged, meta, "")
+	if err := SendEmailChangedNotice(user, previousEmail); err != nil {
+		log.Println("[UpdateProfile] failed to notify the previous email address:", err)
+	}
+	return true, nil
+}
+
+// ChangePassword sets a new password after checking the current one and ends
+// every other session of the user. Wrong current passwords count towards the
+// account lockout like failed logins, so a stolen session cannot be used to
+// guess the password.
+func ChangePassword(user *model.User, current string, newPassword string, keepSessionID string, meta SessionMeta) error {
+	if err := CheckAccountLockout(user, meta.IP); err != nil {
+		return err
+	}
+	if ok, _, err := password.Verify(current, user.Password); err != nil || !ok {
+		if err := RecordLoginFailure(user, meta.IP); err != nil {
+			return err
+		}
+		return ErrWrongPassword
+	}
+
+	if err := CheckNewPassword(user, newPassword); err != nil {
+		return err
+	}
+	hash, err := password.Hash(newPassword)
+	if err != nil {
+		return err
+	}
+	if err := database.DB.Model(&model.User{}).
+		Where("id = ?", user.ID).
+		Update("password", hash).Error; err != nil {
+		return err
+	}
+	user.Password = hash
+	if err := RecordPasswordHistory(user.ID, hash); err != nil {
+		return err
+	}
+
+	if err := RevokeOtherSessions(user.ID, keepSessionID); err != nil {
+		return err
+	}
+	_ = RecordSecurityEvent(user.ID, model.SecurityEventPasswordChanged, meta, "")
+	return nil
+}
+
+// DeleteAccount deletes the user's own account and revokes all its sessions
+func DeleteAccount(user *model.User, meta SessionMeta) error {
+	if err := DeleteUser(user.ID); err != nil {
+		return err
+	}
+	if err := InvalidateUserTokens(user.ID); err != nil {
+		return err
+	}
+	_ = RecordSecurityEvent(user.ID, model.SecurityEventAccountDeleted, meta, "")
+	return nil
+}
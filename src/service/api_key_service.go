@@ -0,0 +1,98 @@
+package service
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"go-journey/src/database"
+	"go-journey/src/model"
+	"go-journey/src/utils"
+	"strings"
+	"time"
+)
+
+// ErrInvalidAPIKey is returned for malformed, unknown, revoked or expired keys
+var ErrInvalidAPIKey = errors.New("invalid API key")
+
+// apiKeyTouchInterval limits how often last-used tracking writes to the database
+const apiKeyTouchInterval = time.Minute
+
+// apiKeyPrefixBytes is the random length of the public key prefix. The prefix
+// is unique, so it must be long enough that collisions stay negligible.
+const apiKeyPrefixBytes = 8
+
+// IsAPIKey reports whether a bearer credential looks like an API key rather than a JWT
+func IsAPIKey(credential string) bool {
+	return strings.HasPrefix(credential, model.APIKeyPrefix+"_")
+}
+
+// CreateAPIKey stores a new key for the user and returns its plaintext, which
+// cannot be recovered later.
+func CreateAPIKey(key *model.APIKey) (string, error) {
+	b := make([]byte, apiKeyPrefixBytes)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	secret, err := utils.RandomToken(32)
+	if err != nil {
+		return "", err
+	}
+
+	key.Prefix = hex.EncodeToString(b)
+	key.KeyHash = utils.HashToken(secret)
+	if err := database.DB.Create(key).Error; err != nil {
+		return "", err
+	}
+	return model.APIKeyPrefix + "_" + key.Prefix + "_" + secret, nil
+}
+
+func GetAPIKeys(userID string) ([]model.APIKey, error) {
+	var keys []model.APIKey
+	result := database.DB.Where("user_id = ? AND revoked_at IS NULL", userID).Order("created_at DESC").Find(&keys)
+	return keys, result.Error
+}
+
+// RevokeAPIKey revokes one of the user's keys. It returns false when the key
+// does not exist, belongs to someone else or is already revoked.
+func RevokeAPIKey(userID string, id string) (bool, error) {
+	result := database.DB.Model(&model.APIKey{}).
+		Where("id = ? AND user_id = ? AND revoked_at IS NULL", id, userID).
+		Update("revoked_at", time.Now())
+	return result.RowsAffected > 0, result.Error
+}
+
+// AuthenticateAPIKey resolves a presented key to its record and owner and
+// records its use.
+func AuthenticateAPIKey(credential string, ip string) (*model.APIKey, *model.User, error) {
+	parts := strings.SplitN(credential, "_", 3)
+	if len(parts) != 3 || parts[0] != model.APIKeyPrefix || parts[1] == "" || parts[2] == "" {
+		return nil, nil, ErrInvalidAPIKey
+	}
+
+	var key model.APIKey
+	if err := database.DB.Where("prefix = ?", parts[1]).First(&key).Error; err != nil {
+		return nil, nil, ErrInvalidAPIKey
+	}
+	if subtle.ConstantTimeCompare([]byte(key.KeyHash), []byte(utils.HashToken(parts[2]))) != 1 {
+		return nil, nil, ErrInvalidAPIKey
+	}
+
+	now := time.Now()
+	if key.RevokedAt != nil || (key.ExpiresAt != nil && now.After(*key.ExpiresAt)) {
+		return nil, nil, ErrInvalidAPIKey
+	}
+
+	user, err := GetUserByID(key.UserID)
+	if err != nil || user.Status != model.UserStatusActive {
+		return nil, nil, ErrInvalidAPIKey
+	}
+
+	if key.LastUsedAt == nil || now.Sub(*key.LastUsedAt) > apiKeyTouchInterval {
+		database.DB.Model(&model.APIKey{}).Where("id = ?", key.ID).Updates(map[string]interface{}{
+			"last_used_at": now,
+			"last_used_ip": ip,
+		})
+	}
+	return &key, &user, nil
+}
@@ -0,0 +1,127 @@
+package service
+
+import (
+	"crypto/subtle"
+	"fmt"
+	"go-journey/src/database"
+	"go-journey/src/mailer"
+	"go-journey/src/model"
+	"go-journey/src/utils"
+	"log"
+	"os"
+	"strings"
+	"time"
+)
+
+// NormalizeEmail lowercases and trims an email address. Emails are stored
+// normalized and are unique regardless of case.
+func NormalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
+// EmailTaken reports whether another user already uses the email
+func EmailTaken(email string, excludeUserID string) bool {
+	var count int64
+	query := database.DB.Model(&model.User{}).Where("LOWER(email) = ?", NormalizeEmail(email))
+	if excludeUserID != "" {
+		query = query.Where("id <> ?", excludeUserID)
+	}
+	query.Count(&count)
+	return count > 0
+}
+
+// EmailVerificationRequired reports whether Login is blocked until the email is verified
+func EmailVerificationRequired() bool {
+	return os.Getenv("REQUIRE_EMAIL_VERIFICATION") == "true"
+}
+
+// SendEmailVerification emails a verification link to the user's address
+func SendEmailVerification(user *model.User) error {
+	if user.Email == "" || user.EmailVerifiedAt != nil {
+		return nil
+	}
+
+	limited, err := userTokenLimitReached(user.ID, model.TokenPurposeEmailVerification, "EMAIL_VERIFICATION")
+	if err != nil {
+		return err
+	}
+	if limited {
+		log.Printf("[EmailVerification] rate limit reached for user %s, link not sent", user.ID)
+		return nil
+	}
+
+	ttl := utils.TTLFromEnv("EMAIL_VERIFICATION_TTL", 24*time.Hour)
+	token, err := issueUserToken(user.ID, model.TokenPurposeEmailVerification, ttl, emailBinding(user.Email))
+	if err != nil {
+		return err
+	}
+
+	return mailer.Send(mailer.Message{
+		To:      user.Email,
+		Subject: "Verify your email address",
+		Body: fmt.Sprintf(
+			"Hi %s,\n\nPlease confirm your email address with the link below. It expires in %s.\n\n%s/verify-email?token=%s\n",
+			user.FullName, ttl, appURL(), token,
+		),
+	})
+}
+
+// SendEmailChangedNotice tells the previous address of an account that its
+// email was changed, so the owner notices a takeover
+func SendEmailChangedNotice(user *model.User, previousEmail string) error {
+	if previousEmail == "" {
+		return nil
+	}
+	return mailer.Send(mailer.Message{
+		To:      previousEmail,
+		Subject: "Your email address was changed",
+		Body: fmt.Sprintf(
+			"Hi %s,\n\nThe email address of your account %s was changed to %s. If you did not do this, reset your password and contact support right away.\n",
+			user.FullName, user.Username, user.Email,
+		),
+	})
+}
+
+// ResendEmailVerification sends a new verification link to an unverified
+// address. It never reveals whether the address is registered.
+func ResendEmailVerification(email string) error {
+	var user model.User
+	if err := database.DB.Where("LOWER(email) = ?", NormalizeEmail(email)).First(&user).Error; err != nil {
+		return nil
+	}
+	return SendEmailVerification(&user)
+}
+
+// VerifyEmail marks the email of the token's user as verified. The token
+// only verifies the address it was sent to, not one the user changed to since.
+func VerifyEmail(token string) error {
+	record, err := ConsumeUserToken(token, model.TokenPurposeEmailVerification)
+	if err != nil {
+		return err
+	}
+
+	var user model.User
+	if err := database.DB.Select("id", "email").Where("id = ?", record.UserID).First(&user).Error; err != nil {
+		return ErrInvalidUserToken
+	}
+	if record.BindingHash == "" || user.Email == "" ||
+		subtle.ConstantTimeCompare([]byte(record.BindingHash), []byte(emailBinding(user.Email))) != 1 {
+		return ErrInvalidUserToken
+	}
+
+	result := database.DB.Model(&model.User{}).
+		Where("id = ? AND email = ?", user.ID, user.Email).
+		Update("email_verified_at", time.Now())
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected != 1 {
+		return ErrInvalidUserToken
+	}
+	return nil
+}
+
+// emailBinding ties a verification token to the address it is sent to
+func emailBinding(email string) string {
+	return utils.HashToken(NormalizeEmail(email))
+}
@@ -0,0 +1,65 @@
+package service
+
+import (
+	"errors"
+	"go-journey/src/database"
+	"go-journey/src/model"
+	"go-journey/src/utils"
+	"log"
+	"time"
+)
+
+var (
+	// ErrImpersonateSelf is returned when an admin tries to impersonate themselves
+	ErrImpersonateSelf = errors.New("cannot impersonate yourself")
+	// ErrImpersonationForbidden is returned for targets that may not be impersonated
+	ErrImpersonationForbidden = errors.New("user cannot be impersonated")
+)
+
+// Impersonation is the result of starting to impersonate a user
+type Impersonation struct {
+	AccessToken string
+	ExpiresAt   time.Time
+}
+
+// Impersonate issues an access token for target on behalf of actor and
+// records it in the audit trail. Inactive users and users who may impersonate
+// others themselves cannot be impersonated.
+func Impersonate(actor *model.User, target *model.User, meta SessionMeta, reason string) (Impersonation, error) {
+	if actor.ID == target.ID {
+		return Impersonation{}, ErrImpersonateSelf
+	}
+	if target.Status != model.UserStatusActive {
+		return Impersonation{}, ErrImpersonationForbidden
+	}
+	privileged, err := RoleHasPermission(target.Role, model.PermUsersImpersonate)
+	if err != nil {
+		return Impersonation{}, err
+	}
+	if privileged {
+		return Impersonation{}, ErrImpersonationForbidden
+	}
+
+	token, jti, exp, err := utils.GenerateImpersonationToken(target, actor)
+	if err != nil {
+		return Impersonation{}, err
+	}
+
+	log.Printf("[Security] %s (%s) started impersonating %s (%s)", actor.Username, actor.ID, target.Username, target.ID)
+	detail := "token " + jti
+	if reason != "" {
+		detail += ": " + reason
+	}
+	if err := database.DB.Create(&model.SecurityEvent{
+		UserID:    target.ID,
+		ActorID:   actor.ID,
+		Type:      model.SecurityEventImpersonation,
+		IP:        meta.IP,
+		UserAgent: meta.UserAgent,
+		Detail:    detail,
+	}).Error; err != nil {
+		return Impersonation{}, err
+	}
+
+	return Impersonation{AccessToken: token, ExpiresAt: exp}, nil
+}
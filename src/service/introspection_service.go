@@ -0,0 +1,132 @@
+package service
+
+import (
+	"errors"
+	"go-journey/src/model"
+	"go-journey/src/utils"
+
+	"github.com/golang-jwt/jwt/v5"
+	"gorm.io/gorm"
+)
+
+// Introspection is the RFC 7662 introspection response. TokenType uses the
+// token_type_hint values, access_token or refresh_token.
+type Introspection struct {
+	Active    bool                   `json:"active"`
+	Scope     string                 `json:"scope,omitempty"`
+	ClientID  string                 `json:"client_id,omitempty"`
+	Username  string                 `json:"username,omitempty"`
+	TokenType string                 `json:"token_type,omitempty"`
+	Exp       int64                  `json:"exp,omitempty"`
+	Iat       int64                  `json:"iat,omitempty"`
+	Sub       string                 `json:"sub,omitempty"`
+	Jti       string                 `json:"jti,omitempty"`
+	Act       map[string]interface{} `json:"act,omitempty"`
+}
+
+// IntrospectToken reports whether a token would currently be accepted:
+// access tokens as by middleware.Auth, refresh tokens as by /auth/refresh.
+// Only confidential clients may introspect, since the answer reveals who a
+// token belongs to.
+func IntrospectToken(client *model.OAuthClient, token string) (Introspection, error) {
+	var inactive Introspection
+	if !client.Confidential() {
+		return inactive, oauthError("invalid_client", "public clients cannot introspect tokens")
+	}
+
+	t, claims, err := utils.ParseToken(token)
+	if err != nil || !t.Valid {
+		return inactive, nil
+	}
+
+	sub, _ := claims["sub"].(string)
+	sid, _ := claims["sid"].(string)
+	result := Introspection{Active: true, Sub: sub}
+
+	switch typ, _ := claims["type"].(string); typ {
+	case "access":
+		if utils.AccessTokenRevoked(claims) {
+			return inactive, nil
+		}
+		result.TokenType = "access_token"
+		result.Jti, _ = claims["jti"].(string)
+		result.Act, _ = claims["act"].(map[string]interface{})
+	case "refresh":
+		if sid == "" || !utils.IsRefreshTokenValid(sid, token) {
+			return inactive, nil
+		}
+		result.TokenType = "refresh_token"
+	default:
+		return inactive, nil
+	}
+
+	user, err := GetUserByID(sub)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return inactive, nil
+	}
+	if err != nil {
+		return inactive, err
+	}
+	if user.Status != model.UserStatusActive {
+		return inactive, nil
+	}
+	result.Username = user.Username
+
+	if sid != "" {
+		session, err := GetSession(sid)
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			return inactive, err
+		}
+		result.ClientID, result.Scope = session.ClientID, session.Scope
+	}
+
+	if exp, _ := claims.GetExpirationTime(); exp != nil {
+		result.Exp = exp.Unix()
+	}
+	if iat, _ := claims.GetIssuedAt(); iat != nil {
+		result.Iat = iat.Unix()
+	}
+	return result, nil
+}
+
+// RevokeToken revokes a token issued to the client (RFC 7009). A refresh
+// token ends its whole session, an access token is denylisted. Invalid and
+// unknown tokens are ignored, as the RFC requires.
+func RevokeToken(client *model.OAuthClient, token string) error {
+	t, claims, err := utils.ParseToken(token)
+	if err != nil || !t.Valid {
+		return nil
+	}
+
+	sid, _ := claims["sid"].(string)
+	if sid == "" {
+		return nil
+	}
+	session, err := GetSession(sid)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if session.ClientID != client.ClientID {
+		return oauthError("unauthorized_client", "the token was not issued to this client")
+	}
+
+	switch typ, _ := claims["type"].(string); typ {
+	case "refresh":
+		return EndSession(sid)
+	case "access":
+		return denyAccessToken(claims)
+	}
+	return nil
+}
+
+func denyAccessToken(claims jwt.MapClaims) error {
+	jti, _ := claims["jti"].(string)
+	exp, _ := claims.GetExpirationTime()
+	if jti == "" || exp == nil {
+		return nil
+	}
+	return utils.DenyToken(jti, exp.Time)
+}
@@ -0,0 +1,197 @@
+package service
+
+import (
+	"fmt"
+	"go-journey/src/database"
+	"go-journey/src/model"
+	"go-journey/src/utils"
+	"log"
+	"time"
+)
+
+// LoginLockedError is returned while an account or IP address is locked out
+type LoginLockedError struct {
+	RetryAfter time.Duration
+}
+
+func (e *LoginLockedError) Error() string {
+	return fmt.Sprintf("login locked, retry after %s", e.RetryAfter)
+}
+
+// lockoutPolicy locks after Threshold consecutive failures, for Base doubled
+// on every further failure and capped at Max. Counters restart after Reset
+// without failures.
+type lockoutPolicy struct {
+	Threshold int
+	Base      time.Duration
+	Max       time.Duration
+	Reset     time.Duration
+}
+
+func accountLockoutPolicy() lockoutPolicy {
+	return lockoutPolicy{
+		Threshold: utils.IntFromEnv("LOGIN_LOCKOUT_THRESHOLD", 5),
+		Base:      utils.TTLFromEnv("LOGIN_LOCKOUT_BASE", time.Minute),
+		Max:       utils.TTLFromEnv("LOGIN_LOCKOUT_MAX", time.Hour),
+		Reset:     utils.TTLFromEnv("LOGIN_FAILURE_RESET", 24*time.Hour),
+	}
+}
+
+func ipLockoutPolicy() lockoutPolicy {
+	p := accountLockoutPolicy()
+	p.Threshold = utils.IntFromEnv("LOGIN_IP_LOCKOUT_THRESHOLD", 20)
+	return p
+}
+
+// lockDuration is how long to lock after the given number of failures
+func (p lockoutPolicy) lockDuration(failures int) time.Duration {
+	if p.Threshold <= 0 || failures < p.Threshold {
+		return 0
+	}
+	d := p.Base
+	for i := p.Threshold; i < failures && d < p.Max; i++ {
+		d *= 2
+	}
+	if d > p.Max {
+		d = p.Max
+	}
+	return d
+}
+
+func lockedError(until *time.Time) error {
+	if until == nil {
+		return nil
+	}
+	if remaining := time.Until(*until); remaining > 0 {
+		return &LoginLockedError{RetryAfter: remaining}
+	}
+	return nil
+}
+
+// CheckIPLockout returns a *LoginLockedError while the IP address is locked
+func CheckIPLockout(ip string) error {
+	var throttle model.LoginThrottle
+	if err := database.DB.Where("ip = ?", ip).Limit(1).Find(&throttle).Error; err != nil {
+		return err
+	}
+	return lockedError(throttle.LockedUntil)
+}
+
+// CheckAccountLockout returns a *LoginLockedError while the account is locked
+// for the IP address
+func CheckAccountLockout(user *model.User, ip string) error {
+	var throttle model.AccountThrottle
+	if err := database.DB.Where("user_id = ? AND ip = ?", user.ID, ip).Limit(1).Find(&throttle).Error; err != nil {
+		return err
+	}
+	return lockedError(throttle.LockedUntil)
+}
+
+// RecordLoginFailure counts a failed attempt against the IP address and, when
+// known, the account from that address, locking either once its threshold is
+// reached. Other addresses can still log in to the account.
+func RecordLoginFailure(user *model.User, ip string) error {
+	now := time.Now()
+
+	ipPolicy := ipLockoutPolicy()
+	var ipFailures int
+	if err := database.DB.Raw(`
+		INSERT INTO login_throttles (ip, failed_count, last_failed_at) VALUES (?, 1, ?)
+		ON CONFLICT (ip) DO UPDATE SET
+			failed_count = CASE WHEN login_throttles.last_failed_at < ? THEN 1 ELSE login_throttles.failed_count + 1 END,
+			last_failed_at = EXCLUDED.last_failed_at
+		RETURNING failed_count`, ip, now, now.Add(-ipPolicy.Reset)).Scan(&ipFailures).Error; err != nil {
+		return err
+	}
+	if d := ipPolicy.lockDuration(ipFailures); d > 0 {
+		if err := database.DB.Model(&model.LoginThrottle{}).Where("ip = ?", ip).
+			Update("locked_until", now.Add(d)).Error; err != nil {
+			return err
+		}
+	}
+
+	if user == nil {
+		return nil
+	}
+
+	policy := accountLockoutPolicy()
+	var failures int
+	if err := database.DB.Raw(`
+		INSERT INTO account_throttles (user_id, ip, failed_count, last_failed_at) VALUES (?, ?, 1, ?)
+		ON CONFLICT (user_id, ip) DO UPDATE SET
+			failed_count = CASE WHEN account_throttles.last_failed_at < ? THEN 1 ELSE account_throttles.failed_count + 1 END,
+			last_failed_at = EXCLUDED.last_failed_at
+		RETURNING failed_count`, user.ID, ip, now, now.Add(-policy.Reset)).Scan(&failures).Error; err != nil {
+		return err
+	}
+	if d := policy.lockDuration(failures); d > 0 {
+		if err := database.DB.Model(&model.AccountThrottle{}).Where("user_id = ? AND ip = ?", user.ID, ip).
+			Update("locked_until", now.Add(d)).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// RecordLoginSuccess clears the failure counter of an account for the IP address
+func RecordLoginSuccess(user *model.User, ip string) error {
+	return database.DB.Where("user_id = ? AND ip = ?", user.ID, ip).Delete(&model.AccountThrottle{}).Error
+}
+
+// GetAccountThrottles returns the failure counters of the given accounts,
+// keyed by user ID
+func GetAccountThrottles(userIDs ...string) (map[string][]model.AccountThrottle, error) {
+	byUser := make(map[string][]model.AccountThrottle, len(userIDs))
+	if len(userIDs) == 0 {
+		return byUser, nil
+	}
+
+	var throttles []model.AccountThrottle
+	if err := database.DB.Where("user_id IN ?", userIDs).Find(&throttles).Error; err != nil {
+		return nil, err
+	}
+	for _, t := range throttles {
+		byUser[t.UserID] = append(byUser[t.UserID], t)
+	}
+	return byUser, nil
+}
+
+// UnlockUser clears the failure counters and lockouts of an account
+func UnlockUser(id string) error {
+	return database.DB.Where("user_id = ?", id).Delete(&model.AccountThrottle{}).Error
+}
+
+// GetLockedIPs lists the IP addresses that are currently locked out
+func GetLockedIPs() ([]model.LoginThrottle, error) {
+	var throttles []model.LoginThrottle
+	result := database.DB.Where("locked_until > ?", time.Now()).Order("locked_until DESC").Find(&throttles)
+	return throttles, result.Error
+}
+
+// UnlockIP forgets the failed attempts of an IP address. It returns false
+// when the address had no recorded failures.
+func UnlockIP(ip string) (bool, error) {
+	result := database.DB.Where("ip = ?", ip).Delete(&model.LoginThrottle{})
+	return result.RowsAffected > 0, result.Error
+}
+
+// StartLoginThrottlePruner removes counters that can no longer lock anything
+func StartLoginThrottlePruner(interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for range ticker.C {
+			now := time.Now()
+			if err := database.DB.
+				Where("last_failed_at < ? AND (locked_until IS NULL OR locked_until < ?)", now.Add(-ipLockoutPolicy().Reset), now).
+				Delete(&model.LoginThrottle{}).Error; err != nil {
+				log.Println("[LoginThrottle] prune failed:", err)
+			}
+			if err := database.DB.
+				Where("last_failed_at < ? AND (locked_until IS NULL OR locked_until < ?)", now.Add(-accountLockoutPolicy().Reset), now).
+				Delete(&model.AccountThrottle{}).Error; err != nil {
+				log.Println("[AccountThrottle] prune failed:", err)
+			}
+		}
+	}()
+}
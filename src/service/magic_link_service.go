@@ -0,0 +1,105 @@
+package service
+
+import (
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"go-journey/src/database"
+	"go-journey/src/mailer"
+	"go-journey/src/model"
+	"go-journey/src/utils"
+	"log"
+	"time"
+)
+
+// ErrMagicLinkDevice is returned when a magic link is opened on another
+// device than the one it was requested from
+var ErrMagicLinkDevice = errors.New("magic link was requested from another device")
+
+// RequestMagicLink emails a single-use login link to the owner of a verified
+// address and returns the device token the requesting client must present
+// with the link. A device token is returned even when no email is sent, so
+// the response never reveals whether the address is registered.
+func RequestMagicLink(email string) (string, error) {
+	deviceToken, err := utils.RandomToken(32)
+	if err != nil {
+		return "", err
+	}
+
+	var user model.User
+	if err := database.DB.Where("LOWER(email) = ?", NormalizeEmail(email)).First(&user).Error; err != nil {
+		return deviceToken, nil
+	}
+	// Unverified addresses may belong to someone else than the account owner
+	if user.MagicLinkDisabled || user.EmailVerifiedAt == nil || user.Status != model.UserStatusActive {
+		return deviceToken, nil
+	}
+
+	limited, err := userTokenLimitReached(user.ID, model.TokenPurposeMagicLink, "MAGIC_LINK")
+	if err != nil {
+		return "", err
+	}
+	if limited {
+		log.Printf("[MagicLink] rate limit reached for user %s, link not sent", user.ID)
+		return deviceToken, nil
+	}
+
+	ttl := utils.TTLFromEnv("MAGIC_LINK_TTL", 10*time.Minute)
+	token, err := issueUserToken(user.ID, model.TokenPurposeMagicLink, ttl, utils.HashToken(deviceToken))
+	if err != nil {
+		return "", err
+	}
+
+	err = mailer.Send(mailer.Message{
+		To:      user.Email,
+		Subject: "Your login link",
+		Body: fmt.Sprintf(
+			"Hi %s,\n\nUse the link below to log in. It expires in %s, can be used once and only works in the browser where you requested it.\n\n%s/magic-link?token=%s\n\nIf you did not request this, you can ignore this email.\n",
+			user.FullName, ttl, appURL(), token,
+		),
+	})
+	// Failing here but not for unknown addresses would reveal the account
+	if err != nil {
+		log.Printf("[MagicLink] failed to send the link to user %s: %v", user.ID, err)
+	}
+	return deviceToken, nil
+}
+
+// VerifyMagicLink uses up a magic link presented with the device token of
+// the request and returns its user.
+func VerifyMagicLink(token string, deviceToken string) (*model.User, error) {
+	record, err := FindUserToken(token, model.TokenPurposeMagicLink)
+	if err != nil {
+		return nil, err
+	}
+	if record.BindingHash == "" ||
+		subtle.ConstantTimeCompare([]byte(record.BindingHash), []byte(utils.HashToken(deviceToken))) != 1 {
+		return nil, ErrMagicLinkDevice
+	}
+
+	if _, err := ConsumeUserToken(token, model.TokenPurposeMagicLink); err != nil {
+		return nil, err
+	}
+
+	user, err := GetUserByID(record.UserID)
+	if err != nil || user.MagicLinkDisabled {
+		return nil, ErrInvalidUserToken
+	}
+	return &user, nil
+}
+
+// SetMagicLinkEnabled turns magic link login on or off for a user. Turning
+// it off also invalidates links that were already sent.
+func SetMagicLinkEnabled(userID string, enabled bool) error {
+	if err := database.DB.Model(&model.User{}).
+		Where("id = ?", userID).
+		Update("magic_link_disabled", !enabled).Error; err != nil {
+		return err
+	}
+	if enabled {
+		return nil
+	}
+	return database.DB.Model(&model.UserToken{}).
+		Where("user_id = ? AND purpose = ? AND used_at IS NULL", userID, model.TokenPurposeMagicLink).
+		Update("used_at", time.Now()).Error
+}
@@ -0,0 +1,219 @@
+package service
+
+import (
+	"crypto/rand"
+	"encoding/base32"
+	"errors"
+	"go-journey/src/database"
+	"go-journey/src/model"
+	"go-journey/src/utils"
+	"os"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrMFAAlreadyEnabled is returned when enrolling a user that already has 2FA
+	ErrMFAAlreadyEnabled = errors.New("two-factor authentication is already enabled")
+	// ErrMFANotEnabled is returned when an action requires 2FA to be enabled
+	ErrMFANotEnabled = errors.New("two-factor authentication is not enabled")
+	// ErrMFAEnrollmentMissing is returned when confirming without a pending enrollment
+	ErrMFAEnrollmentMissing = errors.New("no pending two-factor enrollment")
+	// ErrInvalidMFACode is returned for a wrong, expired or replayed code
+	ErrInvalidMFACode = errors.New("invalid two-factor code")
+)
+
+const recoveryCodeCount = 10
+
+// MFAEnabled reports whether the user has confirmed a TOTP authenticator
+func MFAEnabled(user *model.User) bool {
+	return user.TOTPEnabledAt != nil
+}
+
+// BeginTOTPEnrollment stores a new unconfirmed secret for the user and returns
+// it together with the otpauth:// URI to render as a QR code.
+func BeginTOTPEnrollment(user *model.User) (string, string, error) {
+	if MFAEnabled(user) {
+		return "", "", ErrMFAAlreadyEnabled
+	}
+
+	secret, err := utils.GenerateTOTPSecret()
+	if err != nil {
+		return "", "", err
+	}
+	if err := database.DB.Model(user).Update("totp_secret", secret).Error; err != nil {
+		return "", "", err
+	}
+
+	account := user.Email
+	if account == "" {
+		account = user.Username
+	}
+	return secret, utils.TOTPURI(totpIssuer(), account, secret), nil
+}
+
+// ConfirmTOTPEnrollment enables 2FA once the user proves the authenticator
+// works, and returns the plaintext recovery codes. They are not shown again.
+func ConfirmTOTPEnrollment(user *model.User, code string, meta SessionMeta) ([]string, error) {
+	if MFAEnabled(user) {
+		return nil, ErrMFAAlreadyEnabled
+	}
+	if user.TOTPSecret == "" {
+		return nil, ErrMFAEnrollmentMissing
+	}
+
+	step, ok := utils.ValidateTOTP(user.TOTPSecret, code, time.Now())
+	if !ok {
+		return nil, ErrInvalidMFACode
+	}
+
+	var codes []string
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+		if err := tx.Model(user).Updates(map[string]interface{}{
+			"totp_enabled_at": now,
+			"totp_last_step":  step,
+		}).Error; err != nil {
+			return err
+		}
+		var err error
+		codes, err = replaceRecoveryCodes(tx, user.ID)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	_ = RecordSecurityEvent(user.ID, model.SecurityEventMFAEnabled, meta, "")
+	return codes, nil
+}
+
+// DisableTOTP removes the authenticator and the recovery codes of the user
+func DisableTOTP(user *model.User, meta SessionMeta) error {
+	if !MFAEnabled(user) {
+		return ErrMFANotEnabled
+	}
+
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(user).Updates(map[string]interface{}{
+			"totp_secret":     "",
+			"totp_enabled_at": nil,
+			"totp_last_step":  0,
+		}).Error; err != nil {
+			return err
+		}
+		return tx.Where("user_id = ?", user.ID).Delete(&model.RecoveryCode{}).Error
+	})
+	if err != nil {
+		return err
+	}
+
+	_ = RecordSecurityEvent(user.ID, model.SecurityEventMFADisabled, meta, "")
+	return nil
+}
+
+// RegenerateRecoveryCodes replaces every recovery code of the user
+func RegenerateRecoveryCodes(user *model.User) ([]string, error) {
+	if !MFAEnabled(user) {
+		return nil, ErrMFANotEnabled
+	}
+
+	var codes []string
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		var err error
+		codes, err = replaceRecoveryCodes(tx, user.ID)
+		return err
+	})
+	return codes, err
+}
+
+// VerifyMFACode accepts either a current TOTP code or an unused recovery code.
+// Both are single-use: a TOTP code cannot be replayed within its window.
+func VerifyMFACode(user *model.User, code string, meta SessionMeta) error {
+	if !MFAEnabled(user) {
+		return ErrMFANotEnabled
+	}
+
+	code = strings.TrimSpace(code)
+	if step, ok := utils.ValidateTOTP(user.TOTPSecret, code, time.Now()); ok {
+		result := database.DB.Model(&model.User{}).
+			Where("id = ? AND totp_last_step < ?", user.ID, step).
+			Update("totp_last_step", step)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected != 1 {
+			return ErrInvalidMFACode
+		}
+		return nil
+	}
+
+	result := database.DB.Model(&model.RecoveryCode{}).
+		Where("user_id = ? AND code_hash = ? AND used_at IS NULL", user.ID, utils.HashToken(normalizeRecoveryCode(code))).
+		Update("used_at", time.Now())
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected != 1 {
+		return ErrInvalidMFACode
+	}
+
+	_ = RecordSecurityEvent(user.ID, model.SecurityEventRecoveryCodeUsed, meta, "")
+	return nil
+}
+
+// CountRecoveryCodes returns how many unused recovery codes the user has left
+func CountRecoveryCodes(userID string) (int64, error) {
+	var count int64
+	err := database.DB.Model(&model.RecoveryCode{}).
+		Where("user_id = ? AND used_at IS NULL", userID).
+		Count(&count).Error
+	return count, err
+}
+
+func replaceRecoveryCodes(tx *gorm.DB, userID string) ([]string, error) {
+	if err := tx.Where("user_id = ?", userID).Delete(&model.RecoveryCode{}).Error; err != nil {
+		return nil, err
+	}
+
+	codes := make([]string, 0, recoveryCodeCount)
+	records := make([]model.RecoveryCode, 0, recoveryCodeCount)
+	for i := 0; i < recoveryCodeCount; i++ {
+		code, err := newRecoveryCode()
+		if err != nil {
+			return nil, err
+		}
+		codes = append(codes, code)
+		records = append(records, model.RecoveryCode{
+			UserID:   userID,
+			CodeHash: utils.HashToken(normalizeRecoveryCode(code)),
+		})
+	}
+	if err := tx.Create(&records).Error; err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+// newRecoveryCode returns a code such as "k3vq-7mzd-p2xa"
+func newRecoveryCode() (string, error) {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	s := strings.ToLower(base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString(b))[:12]
+	return s[:4] + "-" + s[4:8] + "-" + s[8:], nil
+}
+
+func normalizeRecoveryCode(code string) string {
+	return strings.ToLower(strings.NewReplacer("-", "", " ", "").Replace(code))
+}
+
+func totpIssuer() string {
+	if issuer := os.Getenv("TOTP_ISSUER"); issuer != "" {
+		return issuer
+	}
+	return "go-journey"
+}
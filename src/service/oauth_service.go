@@ -0,0 +1,205 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"go-journey/src/database"
+	"go-journey/src/model"
+	"go-journey/src/oauth"
+	"go-journey/src/password"
+	"go-journey/src/utils"
+	"regexp"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	// ErrOAuthState is returned when the callback state is unknown, expired or already used
+	ErrOAuthState = errors.New("invalid or expired OAuth state")
+	// ErrOAuthEmailConflict is returned when the provider email belongs to a
+	// local account whose address is not verified, so it cannot be linked safely
+	ErrOAuthEmailConflict = errors.New("email belongs to an unverified account")
+)
+
+// OAuthStateTTL is how long a social login may take to come back
+const OAuthStateTTL = 10 * time.Minute
+
+var usernameSanitizer = regexp.MustCompile(`[^a-z0-9._-]+`)
+
+// BeginOAuthLogin stores a fresh state and PKCE verifier and returns the
+// provider URL to redirect the browser to, along with the state, which the
+// caller must bind to the browser.
+func BeginOAuthLogin(provider *oauth.Provider) (string, string, error) {
+	state, err := utils.RandomToken(32)
+	if err != nil {
+		return "", "", err
+	}
+	verifier, err := utils.RandomToken(48)
+	if err != nil {
+		return "", "", err
+	}
+
+	// Drop abandoned logins while we are here
+	database.DB.Where("expires_at < ?", time.Now()).Delete(&model.OAuthState{})
+
+	record := model.OAuthState{
+		StateHash:    utils.HashToken(state),
+		Provider:     provider.Name,
+		CodeVerifier: verifier,
+		ExpiresAt:    time.Now().Add(OAuthStateTTL),
+	}
+	if err := database.DB.Create(&record).Error; err != nil {
+		return "", "", err
+	}
+	return provider.AuthCodeURL(state, verifier), state, nil
+}
+
+// CompleteOAuthLogin validates the state, exchanges the code and returns the
+// linked or newly created user.
+func CompleteOAuthLogin(ctx context.Context, provider *oauth.Provider, code string, state string) (*model.User, error) {
+	// Deleting with RETURNING makes the state single-use even under concurrency
+	var record model.OAuthState
+	result := database.DB.Clauses(clause.Returning{}).
+		Where("state_hash = ? AND provider = ? AND expires_at > ?", utils.HashToken(state), provider.Name, time.Now()).
+		Delete(&record)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, ErrOAuthState
+	}
+
+	accessToken, err := provider.Exchange(ctx, code, record.CodeVerifier)
+	if err != nil {
+		return nil, err
+	}
+	profile, err := provider.FetchProfile(ctx, accessToken)
+	if err != nil {
+		return nil, err
+	}
+	return linkOrCreateUser(provider.Name, profile)
+}
+
+func linkOrCreateUser(provider string, profile oauth.Profile) (*model.User, error) {
+	var identity model.UserIdentity
+	err := database.DB.Where("provider = ? AND subject = ?", provider, profile.Subject).First(&identity).Error
+	if err == nil {
+		user, err := GetUserByID(identity.UserID)
+		return &user, err
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+
+	email := ""
+	if profile.EmailVerified {
+		email = NormalizeEmail(profile.Email)
+	}
+
+	var user model.User
+	if email != "" {
+		err := database.DB.Where("LOWER(email) = ?", email).First(&user).Error
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, err
+		}
+		if err == nil && user.EmailVerifiedAt == nil {
+			return nil, ErrOAuthEmailConflict
+		}
+	}
+
+	// Social logins cannot bypass the registration mode
+	if user.ID == "" && RegistrationMode() == RegistrationInvite {
+		return nil, ErrInvitationRequired
+	}
+
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		if user.ID == "" {
+			created, err := newOAuthUser(tx, profile, email)
+			if err != nil {
+				return err
+			}
+			user = *created
+		}
+		return tx.Create(&model.UserIdentity{
+			UserID:   user.ID,
+			Provider: provider,
+			Subject:  profile.Subject,
+			Email:    profile.Email,
+		}).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+func newOAuthUser(tx *gorm.DB, profile oauth.Profile, email string) (*model.User, error) {
+	username, err := availableUsername(tx, profile.Username)
+	if err != nil {
+		return nil, err
+	}
+
+	// Social accounts get an unusable random password; a reset sets a real one
+	secret, err := utils.RandomToken(32)
+	if err != nil {
+		return nil, err
+	}
+	hash, err := password.Hash(secret)
+	if err != nil {
+		return nil, err
+	}
+
+	fullName := strings.TrimSpace(profile.Name)
+	if fullName == "" {
+		fullName = username
+	}
+
+	user := model.User{
+		Username: username,
+		Email:    email,
+		FullName: fullName,
+		Password: hash,
+		Role:     model.RoleUser,
+	}
+	if RegistrationMode() == RegistrationApproval {
+		user.Status = model.UserStatusPending
+	}
+	if email != "" {
+		now := time.Now()
+		user.EmailVerifiedAt = &now
+	}
+	if err := tx.Create(&user).Error; err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+func availableUsername(tx *gorm.DB, base string) (string, error) {
+	base = strings.Trim(usernameSanitizer.ReplaceAllString(strings.ToLower(base), "-"), "-")
+	if len(base) < 3 {
+		base = "user"
+	}
+	if len(base) > 90 {
+		base = base[:90]
+	}
+
+	candidate := base
+	for i := 0; i < 5; i++ {
+		var count int64
+		if err := tx.Model(&model.User{}).Where("username = ?", candidate).Count(&count).Error; err != nil {
+			return "", err
+		}
+		if count == 0 {
+			return candidate, nil
+		}
+		suffix, err := utils.RandomToken(3)
+		if err != nil {
+			return "", err
+		}
+		candidate = base + "-" + strings.ToLower(suffix)
+	}
+	return "", errors.New("could not find an available username")
+}
@@ -0,0 +1,367 @@
+package service
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"go-journey/src/database"
+	"go-journey/src/model"
+	"go-journey/src/utils"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"gorm.io/gorm/clause"
+)
+
+const oidcCodeTTL = time.Minute
+
+// OIDCScopes are the scopes the provider understands
+var OIDCScopes = []string{"openid", "profile", "email"}
+
+// OAuthError is an OAuth 2.0 error with its RFC 6749 error code
+type OAuthError struct {
+	Code        string
+	Description string
+}
+
+func (e *OAuthError) Error() string {
+	return e.Code + ": " + e.Description
+}
+
+func oauthError(code string, description string) *OAuthError {
+	return &OAuthError{Code: code, Description: description}
+}
+
+// ErrRedirectURIMismatch is returned when the client or redirect URI of an
+// authorization request cannot be trusted, so the error must not be
+// redirected back to the client.
+var ErrRedirectURIMismatch = errors.New("unknown client or unregistered redirect_uri")
+
+// OIDCEnabled reports whether ID tokens can be issued
+func OIDCEnabled() bool {
+	return utils.IsAsymmetric()
+}
+
+// OIDCIssuer is the issuer identifier and base URL of the provider
+func OIDCIssuer() string {
+	if issuer := os.Getenv("OIDC_ISSUER"); issuer != "" {
+		return strings.TrimRight(issuer, "/")
+	}
+	return appURL()
+}
+
+// OIDCLoginURL is the page that signs the user in and then completes the
+// authorization request with POST /oauth2/authorize
+func OIDCLoginURL() string {
+	if u := os.Getenv("OIDC_LOGIN_URL"); u != "" {
+		return u
+	}
+	return appURL() + "/login"
+}
+
+// AuthorizationRequest holds the parameters of an authorization code request
+type AuthorizationRequest struct {
+	ResponseType        string
+	ClientID            string
+	RedirectURI         string
+	Scope               string
+	State               string
+	Nonce               string
+	CodeChallenge       string
+	CodeChallengeMethod string
+}
+
+// ValidateAuthorizationRequest checks the request. It returns
+// ErrRedirectURIMismatch when the client or redirect URI is invalid, and an
+// *OAuthError for problems that can be reported to the redirect URI.
+func ValidateAuthorizationRequest(req AuthorizationRequest) (*model.OAuthClient, error) {
+	client, err := GetOAuthClient(req.ClientID)
+	if err != nil || !client.AllowsRedirectURI(req.RedirectURI) {
+		return nil, ErrRedirectURIMismatch
+	}
+	if !OIDCEnabled() {
+		return client, oauthError("server_error", "OpenID Connect requires JWT_ALG RS256 or EdDSA")
+	}
+	if req.ResponseType != "code" {
+		return client, oauthError("unsupported_response_type", "only response_type=code is supported")
+	}
+	if !hasScope(req.Scope, "openid") {
+		return client, oauthError("invalid_scope", "the openid scope is required")
+	}
+	for _, s := range strings.Fields(req.Scope) {
+		if !hasScope(strings.Join(OIDCScopes, " "), s) {
+			return client, oauthError("invalid_scope", "unsupported scope "+s)
+		}
+	}
+	if req.CodeChallenge == "" || req.CodeChallengeMethod != "S256" {
+		return client, oauthError("invalid_request", "PKCE with code_challenge_method=S256 is required")
+	}
+	return client, nil
+}
+
+// AuthorizationRedirect builds the redirect back to the client with either a
+// code or an error, always carrying the client's state.
+func AuthorizationRedirect(redirectURI string, params url.Values, state string) string {
+	if state != "" {
+		params.Set("state", state)
+	}
+	sep := "?"
+	if strings.Contains(redirectURI, "?") {
+		sep = "&"
+	}
+	return redirectURI + sep + params.Encode()
+}
+
+// IssueAuthorizationCode creates the single-use code for a validated request
+// approved by the signed-in user.
+func IssueAuthorizationCode(user *model.User, req AuthorizationRequest) (string, error) {
+	code, err := utils.RandomToken(32)
+	if err != nil {
+		return "", err
+	}
+
+	// Drop codes that were never redeemed while we are here
+	database.DB.Where("expires_at < ?", time.Now()).Delete(&model.OAuthAuthorizationCode{})
+
+	record := model.OAuthAuthorizationCode{
+		CodeHash:      utils.HashToken(code),
+		ClientID:      req.ClientID,
+		UserID:        user.ID,
+		RedirectURI:   req.RedirectURI,
+		Scope:         strings.Join(strings.Fields(req.Scope), " "),
+		Nonce:         req.Nonce,
+		CodeChallenge: req.CodeChallenge,
+		ExpiresAt:     time.Now().Add(oidcCodeTTL),
+	}
+	if err := database.DB.Create(&record).Error; err != nil {
+		return "", err
+	}
+	return code, nil
+}
+
+// AuthenticateOAuthClient checks the credentials presented at the token
+// endpoint. Public clients send only their client_id.
+func AuthenticateOAuthClient(clientID string, secret string) (*model.OAuthClient, error) {
+	client, err := GetOAuthClient(clientID)
+	if err != nil {
+		return nil, oauthError("invalid_client", "unknown client")
+	}
+	if client.Confidential() {
+		if secret == "" || subtle.ConstantTimeCompare([]byte(client.SecretHash), []byte(utils.HashToken(secret))) != 1 {
+			return nil, oauthError("invalid_client", "client authentication failed")
+		}
+	}
+	return client, nil
+}
+
+// OIDCTokens is the token endpoint response
+type OIDCTokens struct {
+	AccessToken  string `json:"access_token"`
+	TokenType    string `json:"token_type"`
+	ExpiresIn    int    `json:"expires_in"`
+	RefreshToken string `json:"refresh_token"`
+	IDToken      string `json:"id_token"`
+	Scope        string `json:"scope"`
+}
+
+// RedeemAuthorizationCode exchanges a code for tokens. The user gets a new
+// session bound to the client, so it shows up in their session list.
+func RedeemAuthorizationCode(client *model.OAuthClient, code string, redirectURI string, verifier string, meta SessionMeta) (OIDCTokens, error) {
+	// Deleting with RETURNING makes the code single-use even under concurrency
+	var record model.OAuthAuthorizationCode
+	result := database.DB.Clauses(clause.Returning{}).
+		Where("code_hash = ? AND expires_at > ?", utils.HashToken(code), time.Now()).
+		Delete(&record)
+	if result.Error != nil {
+		return OIDCTokens{}, result.Error
+	}
+	if result.RowsAffected == 0 || record.ClientID != client.ClientID || record.RedirectURI != redirectURI {
+		return OIDCTokens{}, oauthError("invalid_grant", "invalid or expired authorization code")
+	}
+	if !verifyCodeChallenge(verifier, record.CodeChallenge) {
+		return OIDCTokens{}, oauthError("invalid_grant", "code_verifier does not match the code_challenge")
+	}
+
+	user, err := GetUserByID(record.UserID)
+	if err != nil || user.Status != model.UserStatusActive {
+		return OIDCTokens{}, oauthError("invalid_grant", "user is no longer active")
+	}
+
+	meta.DeviceName = client.Name
+	meta.ClientID = client.ClientID
+	meta.Scope = record.Scope
+	tokens, _, err := StartSession(&user, meta)
+	if err != nil {
+		return OIDCTokens{}, err
+	}
+	return oidcTokens(&user, client, tokens, record.Scope, record.Nonce)
+}
+
+// RefreshOIDCTokens rotates the refresh token of a session created for the client
+func RefreshOIDCTokens(client *model.OAuthClient, refreshToken string, meta SessionMeta) (OIDCTokens, error) {
+	invalid := oauthError("invalid_grant", "invalid or expired refresh token")
+
+	t, claims, err := utils.ParseToken(refreshToken)
+	if err != nil || !t.Valid {
+		return OIDCTokens{}, invalid
+	}
+	if typ, _ := claims["type"].(string); typ != "refresh" {
+		return OIDCTokens{}, invalid
+	}
+	sub, _ := claims["sub"].(string)
+	sid, _ := claims["sid"].(string)
+
+	session, err := GetSession(sid)
+	if err != nil || session.ClientID != client.ClientID || session.UserID != sub {
+		return OIDCTokens{}, invalid
+	}
+
+	switch utils.CheckRefreshToken(sid, refreshToken) {
+	case utils.RefreshTokenReused:
+		if err := HandleRefreshTokenReuse(sub, sid, meta); err != nil {
+			return OIDCTokens{}, err
+		}
+		return OIDCTokens{}, invalid
+	case utils.RefreshTokenInvalid:
+		return OIDCTokens{}, invalid
+	}
+
+	user, err := GetUserByID(sub)
+	if err != nil || user.Status != model.UserStatusActive {
+		return OIDCTokens{}, invalid
+	}
+
+	tokens, err := RotateSession(&user, sid, refreshToken)
+	if errors.Is(err, ErrRefreshTokenReused) {
+		if err := HandleRefreshTokenReuse(sub, sid, meta); err != nil {
+			return OIDCTokens{}, err
+		}
+		return OIDCTokens{}, invalid
+	}
+	if err != nil {
+		return OIDCTokens{}, err
+	}
+	return oidcTokens(&user, client, tokens, session.Scope, "")
+}
+
+// UserInfoClaims returns the claims about the user released for a scope
+func UserInfoClaims(user *model.User, scope string) map[string]interface{} {
+	claims := map[string]interface{}{"sub": user.ID}
+	if hasScope(scope, "profile") {
+		claims["name"] = user.FullName
+		claims["preferred_username"] = user.Username
+		claims["updated_at"] = user.UpdatedAt.Unix()
+	}
+	if hasScope(scope, "email") && user.Email != "" {
+		claims["email"] = user.Email
+		claims["email_verified"] = user.EmailVerifiedAt != nil
+	}
+	return claims
+}
+
+func oidcTokens(user *model.User, client *model.OAuthClient, tokens utils.TokenPair, scope string, nonce string) (OIDCTokens, error) {
+	now := time.Now()
+	claims := UserInfoClaims(user, scope)
+	claims["iss"] = OIDCIssuer()
+	claims["aud"] = client.ClientID
+	claims["iat"] = now.Unix()
+	claims["exp"] = now.Add(utils.AccessTokenTTL()).Unix()
+	claims["at_hash"] = accessTokenHash(tokens.AccessToken)
+	if nonce != "" {
+		claims["nonce"] = nonce
+	}
+
+	idToken, err := utils.SignIDToken(claims)
+	if err != nil {
+		return OIDCTokens{}, err
+	}
+
+	return OIDCTokens{
+		AccessToken:  tokens.AccessToken,
+		TokenType:    "Bearer",
+		ExpiresIn:    int(time.Until(tokens.AccessExpiresAt).Seconds()),
+		RefreshToken: tokens.RefreshToken,
+		IDToken:      idToken,
+		Scope:        scope,
+	}, nil
+}
+
+// accessTokenHash is the at_hash claim: the left half of the SHA-256 of the
+// access token. All supported algorithms (RS256, EdDSA) use SHA-256 here.
+func accessTokenHash(accessToken string) string {
+	sum := sha256.Sum256([]byte(accessToken))
+	return base64.RawURLEncoding.EncodeToString(sum[:len(sum)/2])
+}
+
+func verifyCodeChallenge(verifier string, challenge string) bool {
+	if verifier == "" {
+		return false
+	}
+	sum := sha256.Sum256([]byte(verifier))
+	expected := base64.RawURLEncoding.EncodeToString(sum[:])
+	return subtle.ConstantTimeCompare([]byte(expected), []byte(challenge)) == 1
+}
+
+func hasScope(scope string, want string) bool {
+	for _, s := range strings.Fields(scope) {
+		if s == want {
+			return true
+		}
+	}
+	return false
+}
+
+// ===================== CLIENTS =====================
+
+func GetOAuthClients() ([]model.OAuthClient, error) {
+	var clients []model.OAuthClient
+	result := database.DB.Order("name").Find(&clients)
+	return clients, result.Error
+}
+
+func GetOAuthClient(clientID string) (*model.OAuthClient, error) {
+	var client model.OAuthClient
+	if err := database.DB.Where("client_id = ?", clientID).First(&client).Error; err != nil {
+		return nil, err
+	}
+	return &client, nil
+}
+
+// CreateOAuthClient registers a client and returns its secret, which is only
+// generated for confidential clients and cannot be recovered later.
+func CreateOAuthClient(client *model.OAuthClient, confidential bool) (string, error) {
+	clientID, err := utils.RandomToken(16)
+	if err != nil {
+		return "", err
+	}
+	client.ClientID = clientID
+
+	var secret string
+	if confidential {
+		if secret, err = utils.RandomToken(32); err != nil {
+			return "", err
+		}
+		client.SecretHash = utils.HashToken(secret)
+	}
+
+	if err := database.DB.Create(client).Error; err != nil {
+		return "", err
+	}
+	return secret, nil
+}
+
+// DeleteOAuthClient removes a client and ends the sessions it created
+func DeleteOAuthClient(clientID string) (bool, error) {
+	result := database.DB.Where("client_id = ?", clientID).Delete(&model.OAuthClient{})
+	if result.Error != nil || result.RowsAffected == 0 {
+		return false, result.Error
+	}
+	if err := database.DB.Where("client_id = ?", clientID).Delete(&model.OAuthAuthorizationCode{}).Error; err != nil {
+		return true, err
+	}
+	_, err := revokeSessions("client_id = ?", clientID)
+	return true, err
+}
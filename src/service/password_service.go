@@ -0,0 +1,164 @@
+package service
+
+import (
+	"fmt"
+	"go-journey/src/database"
+	"go-journey/src/mailer"
+	"go-journey/src/model"
+	"go-journey/src/password"
+	"go-journey/src/utils"
+	"log"
+	"os"
+	"strings"
+	"time"
+)
+
+// RequestPasswordReset emails a reset link to the user found by username or
+// email, if any. It never reveals whether the account exists.
+func RequestPasswordReset(login string) error {
+	var user model.User
+	if err := database.DB.Where("username = ? OR LOWER(email) = ?", login, NormalizeEmail(login)).
+		First(&user).Error; err != nil {
+		return nil
+	}
+
+	if user.Email == "" {
+		log.Printf("[PasswordReset] user %s has no email address, reset link not sent", user.ID)
+		return nil
+	}
+
+	limited, err := userTokenLimitReached(user.ID, model.TokenPurposePasswordReset, "PASSWORD_RESET")
+	if err != nil {
+		return err
+	}
+	if limited {
+		log.Printf("[PasswordReset] rate limit reached for user %s, reset link not sent", user.ID)
+		return nil
+	}
+
+	ttl := utils.TTLFromEnv("PASSWORD_RESET_TTL", time.Hour)
+	token, err := IssueUserToken(user.ID, model.TokenPurposePasswordReset, ttl)
+	if err != nil {
+		return err
+	}
+
+	return mailer.Send(mailer.Message{
+		To:      user.Email,
+		Subject: "Reset your password",
+		Body: fmt.Sprintf(
+			"Hi %s,\n\nUse the link below to choose a new password. It expires in %s and can be used once.\n\n%s/reset-password?token=%s\n\nIf you did not request this, you can ignore this email.\n",
+			user.FullName, ttl, appURL(), token,
+		),
+	})
+}
+
+// ResetPassword sets a new password using a reset token and revokes every
+// session of the user. The token is only used up once the password passes
+// the policy, so the user can retry with another password.
+func ResetPassword(token string, newPassword string) error {
+	record, err := FindUserToken(token, model.TokenPurposePasswordReset)
+	if err != nil {
+		return err
+	}
+	user, err := GetUserByID(record.UserID)
+	if err != nil {
+		return ErrInvalidUserToken
+	}
+	if err := CheckNewPassword(&user, newPassword); err != nil {
+		return err
+	}
+
+	if _, err := ConsumeUserToken(token, model.TokenPurposePasswordReset); err != nil {
+		return err
+	}
+
+	hash, err := password.Hash(newPassword)
+	if err != nil {
+		return err
+	}
+	if err := database.DB.Model(&model.User{}).
+		Where("id = ?", record.UserID).
+		Update("password", hash).Error; err != nil {
+		return err
+	}
+	if err := RecordPasswordHistory(record.UserID, hash); err != nil {
+		return err
+	}
+
+	return InvalidateUserTokens(record.UserID)
+}
+
+// RehashPassword replaces the stored hash of a verified password with one
+// made with the current hashing parameters. The password itself is unchanged,
+// so sessions and the password history are left alone.
+func RehashPassword(user *model.User, plain string) error {
+	hash, err := password.Hash(plain)
+	if err != nil {
+		return err
+	}
+	result := database.DB.Model(&model.User{}).
+		Where("id = ? AND password = ?", user.ID, user.Password).
+		Update("password", hash)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 1 {
+		user.Password = hash
+	}
+	return nil
+}
+
+// CheckNewPassword applies the password policy to a new password of the
+// user, including the reuse check for existing users. Rejections are
+// returned as *password.PolicyError.
+func CheckNewPassword(user *model.User, candidate string) error {
+	policy := password.PolicyFromEnv()
+	if err := policy.Check(candidate, user.Username, user.Email); err != nil {
+		return err
+	}
+	if user.ID == "" || policy.HistorySize <= 0 {
+		return nil
+	}
+
+	hashes := []string{user.Password}
+	var history []model.PasswordHistory
+	if err := database.DB.Where("user_id = ?", user.ID).
+		Order("created_at DESC").Limit(policy.HistorySize).
+		Find(&history).Error; err != nil {
+		return err
+	}
+	for _, h := range history {
+		hashes = append(hashes, h.PasswordHash)
+	}
+
+	for _, hash := range hashes {
+		if hash != "" && password.Matches(candidate, hash) {
+			return &password.PolicyError{Message: fmt.Sprintf("Password must differ from your last %d passwords", policy.HistorySize)}
+		}
+	}
+	return nil
+}
+
+// RecordPasswordHistory remembers a newly set password hash and forgets the
+// ones that fell out of the policy's history window.
+func RecordPasswordHistory(userID string, hash string) error {
+	size := password.PolicyFromEnv().HistorySize
+	if size <= 0 {
+		return nil
+	}
+
+	if err := database.DB.Create(&model.PasswordHistory{UserID: userID, PasswordHash: hash}).Error; err != nil {
+		return err
+	}
+	return database.DB.Exec(`
+		DELETE FROM password_history WHERE user_id = ? AND id NOT IN (
+			SELECT id FROM password_history WHERE user_id = ? ORDER BY created_at DESC LIMIT ?
+		)`, userID, userID, size).Error
+}
+
+func appURL() string {
+	if url := os.Getenv("APP_URL"); url != "" {
+		return strings.TrimRight(url, "/")
+	}
+	return "http://localhost:8080"
+}
@@ -0,0 +1,206 @@
+package service
+
+import (
+	"errors"
+	"go-journey/src/database"
+	"go-journey/src/model"
+	"sync"
+	"time"
+)
+
+var (
+	// ErrRoleInUse is returned when deleting a role that is still assigned to users
+	ErrRoleInUse = errors.New("role is still assigned to users")
+	// ErrUnknownPermission is returned when a permission name does not exist
+	ErrUnknownPermission = errors.New("unknown permission")
+)
+
+// rolePermissionCacheTTL bounds how long another replica may serve stale permissions
+const rolePermissionCacheTTL = time.Minute
+
+var rolePermissions = struct {
+	sync.RWMutex
+	entries  map[string]map[string]bool
+	loadedAt time.Time
+}{}
+
+// RoleHasPermission reports whether a role grants a permission, from a cache
+// of the role_permissions table.
+func RoleHasPermission(role string, permission string) (bool, error) {
+	entries, err := cachedRolePermissions()
+	if err != nil {
+		return false, err
+	}
+	return entries[role][permission], nil
+}
+
+// RoleGrantsRole reports whether a role grants every permission of another
+// role, so that its holders may hand out the other role without gaining rights.
+// scopes, when not empty, further limits the granting role as for API keys.
+func RoleGrantsRole(role string, other string, scopes []string) (bool, error) {
+	entries, err := cachedRolePermissions()
+	if err != nil {
+		return false, err
+	}
+
+	scoped := uniqueStrings(scopes)
+	for permission := range entries[other] {
+		if !entries[role][permission] || (len(scoped) > 0 && !scoped[permission]) {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+func cachedRolePermissions() (map[string]map[string]bool, error) {
+	rolePermissions.RLock()
+	entries, fresh := rolePermissions.entries, time.Since(rolePermissions.loadedAt) < rolePermissionCacheTTL
+	rolePermissions.RUnlock()
+
+	if entries == nil || !fresh {
+		return loadRolePermissions()
+	}
+	return entries, nil
+}
+
+func loadRolePermissions() (map[string]map[string]bool, error) {
+	var roles []model.Role
+	if err := database.DB.Preload("Permissions").Find(&roles).Error; err != nil {
+		return nil, err
+	}
+
+	entries := make(map[string]map[string]bool, len(roles))
+	for _, r := range roles {
+		perms := make(map[string]bool, len(r.Permissions))
+		for _, p := range r.Permissions {
+			perms[p.Name] = true
+		}
+		entries[r.Name] = perms
+	}
+
+	rolePermissions.Lock()
+	rolePermissions.entries = entries
+	rolePermissions.loadedAt = time.Now()
+	rolePermissions.Unlock()
+	return entries, nil
+}
+
+func invalidateRolePermissions() {
+	rolePermissions.Lock()
+	rolePermissions.entries = nil
+	rolePermissions.Unlock()
+}
+
+// RoleExists reports whether a role with the given name is defined
+func RoleExists(name string) bool {
+	var count int64
+	database.DB.Model(&model.Role{}).Where("name = ?", name).Count(&count)
+	return count > 0
+}
+
+// ===================== ROLES =====================
+
+func GetAllRoles() ([]model.Role, error) {
+	var roles []model.Role
+	result := database.DB.Preload("Permissions").Order("name").Find(&roles)
+	return roles, result.Error
+}
+
+func GetRoleByID(id string) (model.Role, error) {
+	var role model.Role
+	result := database.DB.Preload("Permissions").Where("id = ?", id).First(&role)
+	return role, result.Error
+}
+
+func CreateRole(role *model.Role) error {
+	if err := database.DB.Create(role).Error; err != nil {
+		return err
+	}
+	invalidateRolePermissions()
+	return nil
+}
+
+func UpdateRole(role *model.Role) error {
+	if err := database.DB.Omit("Permissions").Save(role).Error; err != nil {
+		return err
+	}
+	invalidateRolePermissions()
+	return nil
+}
+
+// SetRolePermissions replaces the permissions granted by a role
+func SetRolePermissions(role *model.Role, permissions []model.Permission) error {
+	if err := database.DB.Model(role).Association("Permissions").Replace(permissions); err != nil {
+		return err
+	}
+	invalidateRolePermissions()
+	return nil
+}
+
+func DeleteRole(role *model.Role) error {
+	var count int64
+	if err := database.DB.Model(&model.User{}).Where("role = ?", role.Name).Count(&count).Error; err != nil {
+		return err
+	}
+	if count > 0 {
+		return ErrRoleInUse
+	}
+
+	if err := database.DB.Select("Permissions").Delete(role).Error; err != nil {
+		return err
+	}
+	invalidateRolePermissions()
+	return nil
+}
+
+// ===================== PERMISSIONS =====================
+
+func GetAllPermissions() ([]model.Permission, error) {
+	var permissions []model.Permission
+	result := database.DB.Order("name").Find(&permissions)
+	return permissions, result.Error
+}
+
+// GetPermissionsByName resolves permission names, failing on unknown names
+func GetPermissionsByName(names []string) ([]model.Permission, error) {
+	var permissions []model.Permission
+	if len(names) == 0 {
+		return permissions, nil
+	}
+	if err := database.DB.Where("name IN ?", names).Find(&permissions).Error; err != nil {
+		return nil, err
+	}
+	if len(permissions) != len(uniqueStrings(names)) {
+		return nil, ErrUnknownPermission
+	}
+	return permissions, nil
+}
+
+func GetPermissionByID(id string) (model.Permission, error) {
+	var permission model.Permission
+	result := database.DB.Where("id = ?", id).First(&permission)
+	return permission, result.Error
+}
+
+func CreatePermission(permission *model.Permission) error {
+	return database.DB.Create(permission).Error
+}
+
+func DeletePermission(permission *model.Permission) error {
+	if err := database.DB.Exec("DELETE FROM role_permissions WHERE permission_id = ?", permission.ID).Error; err != nil {
+		return err
+	}
+	if err := database.DB.Delete(permission).Error; err != nil {
+		return err
+	}
+	invalidateRolePermissions()
+	return nil
+}
+
+func uniqueStrings(values []string) map[string]bool {
+	set := make(map[string]bool, len(values))
+	for _, v := range values {
+		set[v] = true
+	}
+	return set
+}
@@ -0,0 +1,53 @@
+package service
+
+import (
+	"errors"
+	"go-journey/src/database"
+	"go-journey/src/model"
+	"go-journey/src/password"
+	"go-journey/src/utils"
+	"time"
+)
+
+// Reauthenticate confirms the user's password, or a TOTP or recovery code
+// when two-factor authentication is enabled, and issues a short-lived
+// elevated access token for the session. Failures count towards the account
+// lockout like failed logins.
+func Reauthenticate(user *model.User, sessionID string, plain string, code string, meta SessionMeta) (string, time.Time, error) {
+	if err := CheckAccountLockout(user, meta.IP); err != nil {
+		return "", time.Time{}, err
+	}
+
+	var err error
+	if plain != "" {
+		if ok, _, verifyErr := password.Verify(plain, user.Password); verifyErr != nil || !ok {
+			err = ErrWrongPassword
+		}
+	} else {
+		err = VerifyMFACode(user, code, meta)
+	}
+	if errors.Is(err, ErrWrongPassword) || errors.Is(err, ErrInvalidMFACode) {
+		if recordErr := RecordLoginFailure(user, meta.IP); recordErr != nil {
+			return "", time.Time{}, recordErr
+		}
+	}
+	if err != nil {
+		return "", time.Time{}, err
+	}
+
+	token, jti, exp, err := utils.GenerateElevatedToken(user, sessionID)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+
+	// Remembered so that ending the session also revokes the elevated token
+	if err := database.DB.Model(&model.Session{}).
+		Where("id = ?", sessionID).
+		Updates(map[string]interface{}{
+			"elevated_jti":        jti,
+			"elevated_expires_at": exp,
+		}).Error; err != nil {
+		return "", time.Time{}, err
+	}
+	return token, exp, nil
+}
@@ -0,0 +1,149 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"go-journey/src/database"
+	"go-journey/src/mailer"
+	"go-journey/src/model"
+	"go-journey/src/utils"
+	"os"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Registration modes, selected with REGISTRATION_MODE
+const (
+	RegistrationOpen     = "open"
+	RegistrationInvite   = "invite"
+	RegistrationApproval = "approval"
+)
+
+var (
+	// ErrInvitationRequired is returned when registering without a code in invite mode
+	ErrInvitationRequired = errors.New("an invitation is required to register")
+	// ErrInvalidInvitation is returned for unknown, expired, used or mismatched codes
+	ErrInvalidInvitation = errors.New("invalid or expired invitation")
+)
+
+// RegistrationMode returns how anonymous callers may create accounts: open,
+// invite (only with an invitation) or approval (pending until activated).
+func RegistrationMode() string {
+	switch mode := strings.ToLower(os.Getenv("REGISTRATION_MODE")); mode {
+	case RegistrationInvite, RegistrationApproval:
+		return mode
+	}
+	return RegistrationOpen
+}
+
+// RegisterUser creates a self-registered user. With an invitation code the
+// user gets the invitation's role and is active in every mode; without one
+// the user is a plain "user", pending approval in approval mode and refused
+// in invite mode.
+func RegisterUser(user *model.User, inviteCode string) error {
+	user.Role = model.RoleUser
+	if inviteCode == "" {
+		switch RegistrationMode() {
+		case RegistrationInvite:
+			return ErrInvitationRequired
+		case RegistrationApproval:
+			user.Status = model.UserStatusPending
+		}
+		return database.DB.Create(user).Error
+	}
+
+	return database.DB.Transaction(func(tx *gorm.DB) error {
+		invitation, err := redeemInvitation(tx, inviteCode, user.Email)
+		if err != nil {
+			return err
+		}
+		user.Role = invitation.Role
+		user.Status = model.UserStatusActive
+		// The code was mailed to this address, which proves it belongs to the user
+		if invitation.Email != "" {
+			now := time.Now()
+			user.EmailVerifiedAt = &now
+		}
+		if err := tx.Create(user).Error; err != nil {
+			return err
+		}
+		return tx.Model(invitation).Update("used_by", user.ID).Error
+	})
+}
+
+// redeemInvitation marks an invitation as used. Updating with RETURNING makes
+// the code single-use even under concurrency.
+func redeemInvitation(tx *gorm.DB, code string, email string) (*model.Invitation, error) {
+	var invitation model.Invitation
+	result := tx.Model(&invitation).Clauses(clause.Returning{}).
+		Where("code_hash = ? AND used_at IS NULL AND expires_at > ?", utils.HashToken(code), time.Now()).
+		Update("used_at", time.Now())
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, ErrInvalidInvitation
+	}
+	if invitation.Email != "" && invitation.Email != NormalizeEmail(email) {
+		return nil, ErrInvalidInvitation
+	}
+	return &invitation, nil
+}
+
+// ===================== INVITATIONS =====================
+
+func GetInvitations() ([]model.Invitation, error) {
+	var invitations []model.Invitation
+	result := database.DB.Order("created_at DESC").Find(&invitations)
+	return invitations, result.Error
+}
+
+// CreateInvitation stores an invitation and returns its code, which cannot be
+// recovered later. Invitations for an address are also emailed.
+func CreateInvitation(invitation *model.Invitation) (string, error) {
+	code, err := utils.RandomToken(24)
+	if err != nil {
+		return "", err
+	}
+	invitation.Email = NormalizeEmail(invitation.Email)
+	invitation.CodeHash = utils.HashToken(code)
+	if err := database.DB.Create(invitation).Error; err != nil {
+		return "", err
+	}
+
+	if invitation.Email == "" {
+		return code, nil
+	}
+	return code, mailer.Send(mailer.Message{
+		To:      invitation.Email,
+		Subject: "You are invited to create an account",
+		Body: fmt.Sprintf(
+			"Hi,\n\nYou have been invited to create an account. Use the link below to register before %s.\n\n%s/register?invite=%s\n",
+			invitation.ExpiresAt.Format(time.RFC1123), appURL(), code,
+		),
+	})
+}
+
+// RevokeInvitation deletes an unused invitation, reporting whether it existed
+func RevokeInvitation(id string) (bool, error) {
+	result := database.DB.Where("id = ? AND used_at IS NULL", id).Delete(&model.Invitation{})
+	return result.RowsAffected > 0, result.Error
+}
+
+// SendAccountApproved tells a pending user that their account was activated
+func SendAccountApproved(user *model.User) error {
+	if user.Email == "" {
+		return nil
+	}
+	return mailer.Send(mailer.Message{
+		To:      user.Email,
+		Subject: "Your account has been approved",
+		Body: fmt.Sprintf(
+			"Hi %s,\n\nAn administrator approved your account. You can now log in at %s.\n",
+			user.FullName, appURL(),
+		),
+	})
+}
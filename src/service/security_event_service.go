@@ -0,0 +1,18 @@
+package service
+
+import (
+	"go-journey/src/database"
+	"go-journey/src/model"
+)
+
+// RecordSecurityEvent stores an audit record for a security relevant incident
+func RecordSecurityEvent(userID string, eventType string, meta SessionMeta, detail string) error {
+	event := model.SecurityEvent{
+		UserID:    userID,
+		Type:      eventType,
+		IP:        meta.IP,
+		UserAgent: meta.UserAgent,
+		Detail:    detail,
+	}
+	return database.DB.Create(&event).Error
+}
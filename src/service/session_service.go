@@ -0,0 +1,164 @@
+package service
+
+import (
+	"errors"
+	"go-journey/src/database"
+	"go-journey/src/model"
+	"go-journey/src/utils"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ErrRefreshTokenReused is returned when a rotated refresh token is presented again
+var ErrRefreshTokenReused = errors.New("refresh token reused")
+
+// SessionMeta describes the device a session is created from. ClientID and
+// Scope are set for sessions created through the OpenID Connect provider.
+type SessionMeta struct {
+	DeviceName string
+	UserAgent  string
+	IP         string
+	ClientID   string
+	Scope      string
+}
+
+// StartSession creates a new device session and issues its first token pair.
+// Sessions of OpenID Connect clients get no login time, so their tokens never
+// pass RequireRecentAuth.
+func StartSession(user *model.User, meta SessionMeta) (utils.TokenPair, *model.Session, error) {
+	now := time.Now()
+	session := model.Session{
+		ID:         uuid.New().String(),
+		UserID:     user.ID,
+		DeviceName: meta.DeviceName,
+		UserAgent:  meta.UserAgent,
+		IP:         meta.IP,
+		ClientID:   meta.ClientID,
+		Scope:      meta.Scope,
+		LastUsedAt: now,
+		ExpiresAt:  now.Add(utils.RefreshTokenTTL()),
+	}
+	if meta.ClientID == "" {
+		session.AuthenticatedAt = &now
+	}
+	if err := database.DB.Create(&session).Error; err != nil {
+		return utils.TokenPair{}, nil, err
+	}
+
+	tokens, err := utils.GenerateTokenPair(user, &session)
+	if err != nil {
+		return utils.TokenPair{}, nil, err
+	}
+	if err := utils.SaveRefreshToken(user.ID, session.ID, tokens); err != nil {
+		return utils.TokenPair{}, nil, err
+	}
+	return tokens, &session, nil
+}
+
+// RotateSession exchanges the presented refresh token for a new token pair
+// in the same family. It fails with ErrRefreshTokenReused if the token was
+// already rotated by a concurrent request.
+func RotateSession(user *model.User, sessionID string, refreshToken string) (utils.TokenPair, error) {
+	consumed, err := utils.ConsumeRefreshToken(sessionID, refreshToken)
+	if err != nil {
+		return utils.TokenPair{}, err
+	}
+	if !consumed {
+		return utils.TokenPair{}, ErrRefreshTokenReused
+	}
+
+	// Refreshing is not a new login, so the tokens keep the original auth_time
+	session, err := GetSession(sessionID)
+	if err != nil {
+		return utils.TokenPair{}, err
+	}
+	tokens, err := utils.GenerateTokenPair(user, &session)
+	if err != nil {
+		return utils.TokenPair{}, err
+	}
+	if err := utils.SaveRefreshToken(user.ID, sessionID, tokens); err != nil {
+		return utils.TokenPair{}, err
+	}
+	return tokens, nil
+}
+
+// HandleRefreshTokenReuse revokes a compromised token family and records it
+func HandleRefreshTokenReuse(userID string, sessionID string, meta SessionMeta) error {
+	log.Printf("[Security] refresh token reuse detected for user %s, session %s revoked", userID, sessionID)
+
+	if err := EndSession(sessionID); err != nil {
+		return err
+	}
+	return RecordSecurityEvent(userID, model.SecurityEventRefreshTokenReuse, meta, "session "+sessionID+" revoked")
+}
+
+// GetSession returns a session by ID
+func GetSession(sessionID string) (model.Session, error) {
+	var session model.Session
+	result := database.DB.Where("id = ?", sessionID).First(&session)
+	return session, result.Error
+}
+
+// GetActiveSessions lists the sessions of a user that are neither revoked nor expired
+func GetActiveSessions(userID string) ([]model.Session, error) {
+	var sessions []model.Session
+	result := database.DB.
+		Where("user_id = ? AND revoked_at IS NULL AND expires_at > ?", userID, time.Now()).
+		Order("last_used_at DESC").
+		Find(&sessions)
+	return sessions, result.Error
+}
+
+// EndSession revokes a session and denylists its outstanding access token
+func EndSession(sessionID string) error {
+	_, err := revokeSessions("id = ?", sessionID)
+	return err
+}
+
+// RevokeSession revokes one session of a user, reporting whether it existed
+func RevokeSession(userID string, sessionID string) (bool, error) {
+	count, err := revokeSessions("id = ? AND user_id = ?", sessionID, userID)
+	return count > 0, err
+}
+
+// RevokeAllSessions revokes every active session of a user and denylists
+// their outstanding access tokens
+func RevokeAllSessions(userID string) error {
+	_, err := revokeSessions("user_id = ?", userID)
+	return err
+}
+
+// RevokeOtherSessions revokes every active session of a user except one
+func RevokeOtherSessions(userID string, keepSessionID string) error {
+	_, err := revokeSessions("user_id = ? AND id <> ?", userID, keepSessionID)
+	return err
+}
+
+func revokeSessions(query string, args ...interface{}) (int64, error) {
+	var sessions []model.Session
+	if err := database.DB.Where(query, args...).Where("revoked_at IS NULL").
+		Find(&sessions).Error; err != nil {
+		return 0, err
+	}
+	if len(sessions) == 0 {
+		return 0, nil
+	}
+
+	ids := make([]string, 0, len(sessions))
+	for _, s := range sessions {
+		if err := utils.DenyToken(s.AccessJTI, s.AccessExpiresAt); err != nil {
+			return 0, err
+		}
+		if err := utils.DenyToken(s.ElevatedJTI, s.ElevatedExpiresAt); err != nil {
+			return 0, err
+		}
+		ids = append(ids, s.ID)
+	}
+
+	result := database.DB.Model(&model.Session{}).
+		Where("id IN ?", ids).
+		Update("revoked_at", time.Now())
+	return result.RowsAffected, result.Error
+}
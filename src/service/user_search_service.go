@@ -0,0 +1,132 @@
+package service
+
+import (
+	"go-journey/src/database"
+	"go-journey/src/model"
+	"go-journey/src/res"
+	"go-journey/src/utils"
+	"log"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// searchSimilarityThreshold matches pg_trgm's default similarity_threshold
+const searchSimilarityThreshold = 0.3
+
+// UserSearchResult is a user matched by SearchUsers, with its similarity to
+// the query and the matching words of each field marked
+type UserSearchResult struct {
+	res.UserResponse
+	Score     float64       `json:"score"`
+	Highlight UserHighlight `json:"highlight"`
+}
+
+// userSearchRow is a matched user as read from the database
+type userSearchRow struct {
+	model.User
+	Score float64 `gorm:"column:score"`
+}
+
+// UserHighlight holds HTML-escaped fields with matches wrapped in <mark>
+type UserHighlight struct {
+	Username string `json:"username"`
+	FullName string `json:"full_name"`
+}
+
+var (
+	trigramOnce      sync.Once
+	trigramAvailable bool
+)
+
+// trigramSearchAvailable reports whether the pg_trgm extension is installed.
+// The migration creates it when the database allows.
+func trigramSearchAvailable() bool {
+	trigramOnce.Do(func() {
+		err := database.DB.Raw("SELECT EXISTS (SELECT 1 FROM pg_extension WHERE extname = 'pg_trgm')").
+			Scan(&trigramAvailable).Error
+		if err != nil {
+			log.Println("⚠️ Checking for pg_trgm failed, using LIKE search:", err)
+			trigramAvailable = false
+		}
+	})
+	return trigramAvailable
+}
+
+// SearchUsers finds users whose username or full name resembles the query,
+// best matches first. Without pg_trgm it falls back to LIKE matching of the
+// query's words, ranked by the same similarity computed in Go.
+func SearchUsers(query string, limit int) ([]UserSearchResult, error) {
+	query = strings.TrimSpace(query)
+
+	var rows []userSearchRow
+	var err error
+	if trigramSearchAvailable() {
+		rows, err = searchUsersTrigram(query, limit)
+	} else {
+		rows, err = searchUsersLike(query, limit)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]UserSearchResult, len(rows))
+	for i := range rows {
+		results[i] = UserSearchResult{
+			UserResponse: res.NewUserResponse(&rows[i].User),
+			Score:        rows[i].Score,
+			Highlight: UserHighlight{
+				Username: utils.HighlightMatches(rows[i].Username, query, searchSimilarityThreshold),
+				FullName: utils.HighlightMatches(rows[i].FullName, query, searchSimilarityThreshold),
+			},
+		}
+	}
+	return results, nil
+}
+
+// searchUsersTrigram uses the GIN trigram indexes on username and full_name.
+// word_similarity lets a query match part of a longer full name.
+func searchUsersTrigram(query string, limit int) ([]userSearchRow, error) {
+	var results []userSearchRow
+	err := database.DB.Model(&model.User{}).
+		Select("users.*, GREATEST(similarity(username, ?), similarity(full_name, ?), word_similarity(?, full_name)) AS score", query, query, query).
+		Where("username % ? OR full_name % ? OR ? <% full_name", query, query, query).
+		Order("score DESC").
+		Order("username").
+		Limit(limit).
+		Scan(&results).Error
+	return results, err
+}
+
+func searchUsersLike(query string, limit int) ([]userSearchRow, error) {
+	words := strings.Fields(strings.ToLower(query))
+	if len(words) == 0 {
+		return nil, nil
+	}
+
+	conditions := database.DB
+	for _, word := range words {
+		pattern := "%" + likeEscaper.Replace(word) + "%"
+		conditions = conditions.Or("(LOWER(username) LIKE ? OR LOWER(full_name) LIKE ?)", pattern, pattern)
+	}
+
+	// Rank a few more candidates than requested, since LIKE cannot order by similarity
+	var results []userSearchRow
+	if err := database.DB.Model(&model.User{}).Where(conditions).Order("username").Limit(limit * 5).Scan(&results).Error; err != nil {
+		return nil, err
+	}
+
+	for i := range results {
+		results[i].Score = max(
+			utils.TrigramSimilarity(query, results[i].Username),
+			utils.TrigramSimilarity(query, results[i].FullName),
+		)
+	}
+	sort.SliceStable(results, func(i, j int) bool {
+		return results[i].Score > results[j].Score
+	})
+	if len(results) > limit {
+		results = results[:limit]
+	}
+	return results, nil
+}
@@ -1,16 +1,213 @@
 package service
 
 import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
 	"go-journey/src/database"
 	"go-journey/src/model"
+	"go-journey/src/utils"
 	"log"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrInvalidSort is returned for sort fields that are not whitelisted
+	ErrInvalidSort = errors.New("invalid sort field")
+	// ErrInvalidCursor is returned for malformed cursors or cursors of another sort
+	ErrInvalidCursor = errors.New("invalid cursor")
 )
 
-// GetAllUsers fetches all users from DB
-func GetAllUsers() ([]model.User, error) {
+// userSortFields are the columns GET /users can be sorted by
+var userSortFields = map[string]string{
+	"username":      "username",
+	"full_name":     "full_name",
+	"email":         "email",
+	"role":          "role",
+	"register_date": "register_date",
+}
+
+// UserFilter selects one page of users. Cursor, when set, continues after
+// the last user of the previous page and takes precedence over Page.
+type UserFilter struct {
+	Page           int
+	PerPage        int
+	Cursor         string
+	Sort           string
+	Query          string
+	Role           string
+	Status         string
+	EsignStatusID  string
+	RegisteredFrom *time.Time
+	RegisteredTo   *time.Time
+}
+
+// UserPage is one page of users. Total is only counted for page based requests.
+type UserPage struct {
+	Users      []model.User
+	Total      *int64
+	HasMore    bool
+	NextCursor string
+}
+
+// UserCursor is the position after the last user of a page, for keyset
+// pagination in the order given by Sort
+type UserCursor struct {
+	Sort  string `json:"s"`
+	Value string `json:"v"`
+	ID    string `json:"id"`
+}
+
+// ListUsers returns a filtered, sorted page of users
+func ListUsers(filter UserFilter) (UserPage, error) {
+	column, direction, err := UserSortOrder(filter.Sort)
+	if err != nil {
+		return UserPage{}, err
+	}
+
+	query := filterUsers(database.DB.Model(&model.User{}), filter)
+
+	var page UserPage
+	if filter.Cursor != "" {
+		cursor, err := DecodeUserCursor(filter.Cursor, filter.Sort)
+		if err != nil {
+			return UserPage{}, err
+		}
+		value, _ := cursorValue(column, cursor.Value)
+		op := ">"
+		if direction == "DESC" {
+			op = "<"
+		}
+		query = query.Where(
+			fmt.Sprintf("(%s %s ?) OR (%s = ? AND id %s ?)", column, op, column, op),
+			value, value, cursor.ID,
+		)
+	} else {
+		var total int64
+		if err := query.Session(&gorm.Session{}).Count(&total).Error; err != nil {
+			return UserPage{}, err
+		}
+		page.Total = &total
+		query = query.Offset((filter.Page - 1) * filter.PerPage)
+	}
+
+	// One extra row tells whether another page follows
 	var users []model.User
-	result := database.DB.Find(&users)
-	return users, result.Error
+	if err := query.
+		Order(column + " " + direction).
+		Order("id " + direction).
+		Limit(filter.PerPage + 1).
+		Find(&users).Error; err != nil {
+		return UserPage{}, err
+	}
+
+	if len(users) > filter.PerPage {
+		users = users[:filter.PerPage]
+		page.HasMore = true
+		page.NextCursor = EncodeUserCursor(filter.Sort, &users[len(users)-1])
+	}
+	page.Users = users
+	return page, nil
+}
+
+func filterUsers(query *gorm.DB, filter UserFilter) *gorm.DB {
+	if filter.Role != "" {
+		query = query.Where("role = ?", filter.Role)
+	}
+	if filter.Status != "" {
+		query = query.Where("status = ?", filter.Status)
+	}
+	if filter.EsignStatusID != "" {
+		query = query.Where("esign_status_id = ?", filter.EsignStatusID)
+	}
+	if filter.RegisteredFrom != nil {
+		query = query.Where("register_date >= ?", *filter.RegisteredFrom)
+	}
+	if filter.RegisteredTo != nil {
+		query = query.Where("register_date < ?", *filter.RegisteredTo)
+	}
+	if q := strings.TrimSpace(filter.Query); q != "" {
+		pattern := "%" + likeEscaper.Replace(strings.ToLower(q)) + "%"
+		query = query.Where("LOWER(username) LIKE ? OR LOWER(full_name) LIKE ?", pattern, pattern)
+	}
+	return query
+}
+
+// likeEscaper escapes the LIKE wildcards of user input (backslash is the
+// default escape character in PostgreSQL)
+var likeEscaper = strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`)
+
+// UserSortOrder validates a sort parameter, a whitelisted field optionally
+// prefixed with "-" for descending, and returns its column and direction.
+// The default is the newest users first.
+func UserSortOrder(sort string) (string, string, error) {
+	sort = normalizeUserSort(sort)
+	column, ok := userSortFields[strings.TrimPrefix(sort, "-")]
+	if !ok {
+		return "", "", ErrInvalidSort
+	}
+	if strings.HasPrefix(sort, "-") {
+		return column, "DESC", nil
+	}
+	return column, "ASC", nil
+}
+
+func normalizeUserSort(sort string) string {
+	if sort == "" {
+		return "-register_date"
+	}
+	return sort
+}
+
+// EncodeUserCursor returns the opaque cursor continuing after last in the
+// order given by sort
+func EncodeUserCursor(sort string, last *model.User) string {
+	column, _, _ := UserSortOrder(sort)
+	var value string
+	switch column {
+	case "username":
+		value = last.Username
+	case "full_name":
+		value = last.FullName
+	case "email":
+		value = last.Email
+	case "role":
+		value = last.Role
+	case "register_date":
+		value = last.RegisterDate.UTC().Format(time.RFC3339Nano)
+	}
+	raw, _ := json.Marshal(UserCursor{Sort: normalizeUserSort(sort), Value: value, ID: last.ID})
+	return base64.RawURLEncoding.EncodeToString(raw)
+}
+
+// DecodeUserCursor parses a cursor from EncodeUserCursor. Cursors that are
+// malformed or were issued for another sort fail with ErrInvalidCursor.
+func DecodeUserCursor(encoded string, sort string) (UserCursor, error) {
+	column, _, err := UserSortOrder(sort)
+	if err != nil {
+		return UserCursor{}, err
+	}
+
+	var cursor UserCursor
+	raw, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil || json.Unmarshal(raw, &cursor) != nil || cursor.Sort != normalizeUserSort(sort) || cursor.ID == "" {
+		return UserCursor{}, ErrInvalidCursor
+	}
+	if _, err := cursorValue(column, cursor.Value); err != nil {
+		return UserCursor{}, ErrInvalidCursor
+	}
+	return cursor, nil
+}
+
+func cursorValue(column string, value string) (interface{}, error) {
+	if column == "register_date" {
+		return time.Parse(time.RFC3339Nano, value)
+	}
+	return value, nil
 }
 
 // GetUserByID fetches a single user by UUID
@@ -32,6 +229,26 @@ func UpdateUser(user *model.User) error {
 	return result.Error
 }
 
+// SetUserStatus activates or deactivates a user. Deactivation revokes every session.
+func SetUserStatus(id string, status string) error {
+	if err := database.DB.Model(&model.User{}).Where("id = ?", id).Update("status", status).Error; err != nil {
+		return err
+	}
+	if status != model.UserStatusActive {
+		return InvalidateUserTokens(id)
+	}
+	return nil
+}
+
+// InvalidateUserTokens revokes every session of a user and bumps the token
+// version so that all outstanding access tokens stop being accepted.
+func InvalidateUserTokens(id string) error {
+	if err := utils.BumpTokenVersion(id); err != nil {
+		return err
+	}
+	return RevokeAllSessions(id)
+}
+
 func DeleteUser(id string) error {
 	result := database.DB.Delete(&model.User{}, "id = ?", id)
 	if result.Error != nil {
@@ -0,0 +1,96 @@
+package service
+
+import (
+	"errors"
+	"go-journey/src/database"
+	"go-journey/src/model"
+	"go-journey/src/utils"
+	"time"
+)
+
+// ErrInvalidUserToken is returned for unknown, expired or already used tokens
+var ErrInvalidUserToken = errors.New("invalid or expired token")
+
+// IssueUserToken creates a single-use token for a purpose and returns its
+// plaintext. Earlier unused tokens of the same purpose are invalidated.
+func IssueUserToken(userID string, purpose string, ttl time.Duration) (string, error) {
+	return issueUserToken(userID, purpose, ttl, "")
+}
+
+// userTokenLimitReached reports whether the user was already sent max tokens
+// of a purpose within window, so that one address cannot be mail-bombed.
+// The limit is read from <prefix>_MAX_PER_ADDRESS and <prefix>_RATE_WINDOW.
+func userTokenLimitReached(userID string, purpose string, prefix string) (bool, error) {
+	max := utils.IntFromEnv(prefix+"_MAX_PER_ADDRESS", 3)
+	window := utils.TTLFromEnv(prefix+"_RATE_WINDOW", 15*time.Minute)
+
+	var sent int64
+	if err := database.DB.Model(&model.UserToken{}).
+		Where("user_id = ? AND purpose = ? AND created_at > ?", userID, purpose, time.Now().Add(-window)).
+		Count(&sent).Error; err != nil {
+		return false, err
+	}
+	return sent >= int64(max), nil
+}
+
+func issueUserToken(userID string, purpose string, ttl time.Duration, bindingHash string) (string, error) {
+	token, err := utils.RandomToken(32)
+	if err != nil {
+		return "", err
+	}
+
+	now := time.Now()
+	if err := RevokeUserTokens(userID, purpose); err != nil {
+		return "", err
+	}
+
+	record := model.UserToken{
+		UserID:      userID,
+		Purpose:     purpose,
+		TokenHash:   utils.HashToken(token),
+		BindingHash: bindingHash,
+		ExpiresAt:   now.Add(ttl),
+	}
+	if err := database.DB.Create(&record).Error; err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+// RevokeUserTokens invalidates the unused tokens of a purpose, e.g. links
+// sent to an address the user no longer has
+func RevokeUserTokens(userID string, purpose string) error {
+	return database.DB.Model(&model.UserToken{}).
+		Where("user_id = ? AND purpose = ? AND used_at IS NULL", userID, purpose).
+		Update("used_at", time.Now()).Error
+}
+
+// FindUserToken validates a token for a purpose without using it up
+func FindUserToken(token string, purpose string) (*model.UserToken, error) {
+	var record model.UserToken
+	if err := database.DB.
+		Where("token_hash = ? AND purpose = ? AND used_at IS NULL AND expires_at > ?", utils.HashToken(token), purpose, time.Now()).
+		First(&record).Error; err != nil {
+		return nil, ErrInvalidUserToken
+	}
+	return &record, nil
+}
+
+// ConsumeUserToken validates a token for a purpose and marks it as used
+func ConsumeUserToken(token string, purpose string) (*model.UserToken, error) {
+	record, err := FindUserToken(token, purpose)
+	if err != nil {
+		return nil, err
+	}
+
+	result := database.DB.Model(&model.UserToken{}).
+		Where("id = ? AND used_at IS NULL", record.ID).
+		Update("used_at", time.Now())
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected != 1 {
+		return nil, ErrInvalidUserToken
+	}
+	return record, nil
+}
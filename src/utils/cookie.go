@@ -0,0 +1,143 @@
+package utils
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+const (
+	// AuthModeHeader lets browser clients opt in to cookie sessions with "cookie"
+	AuthModeHeader = "X-Auth-Mode"
+
+	AccessTokenCookie  = "access_token"
+	RefreshTokenCookie = "refresh_token"
+	// CSRFCookie is readable by scripts, which echo it in CSRFHeader
+	CSRFCookie = "csrf_token"
+	CSRFHeader = "X-CSRF-Token"
+
+	// OAuthStateCookie binds a social login to the browser that started it
+	OAuthStateCookie = "oauth_state"
+
+	// The refresh token is only needed by /auth/refresh and /auth/logout
+	refreshCookiePath = "/auth"
+	oauthCookiePath   = "/auth/oauth"
+)
+
+// CookieMode reports whether the client asked for tokens in cookies
+func CookieMode(c *fiber.Ctx) bool {
+	return strings.EqualFold(c.Get(AuthModeHeader), "cookie")
+}
+
+// SetAuthCookies stores a token pair in HttpOnly cookies and returns the
+// session's CSRF token, which the client must send in the X-CSRF-Token header.
+func SetAuthCookies(c *fiber.Ctx, tokens TokenPair, sessionID string) string {
+	csrf := CSRFToken(sessionID)
+	refreshExpires := time.Now().Add(RefreshTokenTTL())
+	c.Cookie(authCookie(AccessTokenCookie, tokens.AccessToken, "/", tokens.AccessExpiresAt, true))
+	c.Cookie(authCookie(RefreshTokenCookie, tokens.RefreshToken, refreshCookiePath, refreshExpires, true))
+	c.Cookie(authCookie(CSRFCookie, csrf, "/", refreshExpires, false))
+	return csrf
+}
+
+// SetAccessTokenCookie replaces only the access token cookie, e.g. with an
+// elevated token
+func SetAccessTokenCookie(c *fiber.Ctx, token string, expires time.Time) {
+	c.Cookie(authCookie(AccessTokenCookie, token, "/", expires, true))
+}
+
+// ClearAuthCookies expires the cookies set by SetAuthCookies
+func ClearAuthCookies(c *fiber.Ctx) {
+	past := time.Unix(0, 0)
+	c.Cookie(authCookie(AccessTokenCookie, "", "/", past, true))
+	c.Cookie(authCookie(RefreshTokenCookie, "", refreshCookiePath, past, true))
+	c.Cookie(authCookie(CSRFCookie, "", "/", past, false))
+}
+
+// SetOAuthStateCookie remembers the state of a social login in the browser.
+// The provider redirects back cross-site, so the cookie is SameSite=Lax.
+func SetOAuthStateCookie(c *fiber.Ctx, state string, expires time.Time) {
+	cookie := authCookie(OAuthStateCookie, state, oauthCookiePath, expires, true)
+	cookie.SameSite = fiber.CookieSameSiteLaxMode
+	c.Cookie(cookie)
+}
+
+// OAuthStateMatches reports whether the callback state is the one this
+// browser started with, and forgets it. Without the check an attacker could
+// send a victim a callback URL with the attacker's own code and state.
+func OAuthStateMatches(c *fiber.Ctx, state string) bool {
+	cookie := c.Cookies(OAuthStateCookie)
+	expired := authCookie(OAuthStateCookie, "", oauthCookiePath, time.Unix(0, 0), true)
+	expired.SameSite = fiber.CookieSameSiteLaxMode
+	c.Cookie(expired)
+	return cookie != "" && subtle.ConstantTimeCompare([]byte(cookie), []byte(state)) == 1
+}
+
+// CSRFToken derives the CSRF token of a session as an HMAC of its ID. Unlike
+// a random token echoed from a cookie, it cannot be planted by a sibling
+// subdomain that shares COOKIE_DOMAIN and sets its own cookies.
+func CSRFToken(sessionID string) string {
+	mac := hmac.New(sha256.New, csrfSecret())
+	mac.Write([]byte("csrf:" + sessionID))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// ValidCSRF reports whether the X-CSRF-Token header carries the CSRF token of
+// the session the request's cookies belong to.
+func ValidCSRF(c *fiber.Ctx, sessionID string) bool {
+	header := c.Get(CSRFHeader)
+	return sessionID != "" && CSRFSecretConfigured() && hmac.Equal([]byte(header), []byte(CSRFToken(sessionID)))
+}
+
+// CSRFSecretConfigured reports whether there is a key for the CSRF tokens.
+// Without one anybody could compute the token of a session from its ID.
+func CSRFSecretConfigured() bool {
+	return len(csrfSecret()) > 0
+}
+
+// csrfSecret is CSRF_SECRET, or JWT_SECRET when tokens are signed with it
+func csrfSecret() []byte {
+	if secret := os.Getenv("CSRF_SECRET"); secret != "" {
+		return []byte(secret)
+	}
+	return []byte(os.Getenv("JWT_SECRET"))
+}
+
+// SafeMethod reports whether a request method cannot change state
+func SafeMethod(method string) bool {
+	switch method {
+	case fiber.MethodGet, fiber.MethodHead, fiber.MethodOptions:
+		return true
+	}
+	return false
+}
+
+func authCookie(name string, value string, path string, expires time.Time, httpOnly bool) *fiber.Cookie {
+	return &fiber.Cookie{
+		Name:     name,
+		Value:    value,
+		Path:     path,
+		Domain:   os.Getenv("COOKIE_DOMAIN"),
+		Expires:  expires,
+		Secure:   os.Getenv("COOKIE_SECURE") != "false",
+		HTTPOnly: httpOnly,
+		SameSite: cookieSameSite(),
+	}
+}
+
+func cookieSameSite() string {
+	switch strings.ToLower(os.Getenv("COOKIE_SAMESITE")) {
+	case "lax":
+		return fiber.CookieSameSiteLaxMode
+	case "none":
+		return fiber.CookieSameSiteNoneMode
+	default:
+		return fiber.CookieSameSiteStrictMode
+	}
+}
@@ -0,0 +1,122 @@
+package utils
+
+import (
+	"log"
+	"os"
+	"sync"
+	"time"
+
+	"go-journey/src/database"
+	"go-journey/src/model"
+
+	"gorm.io/gorm/clause"
+)
+
+// DenylistStore keeps the JTIs of revoked access tokens until they expire
+type DenylistStore interface {
+	Add(jti string, expiresAt time.Time) error
+	Contains(jti string) (bool, error)
+	Prune() error
+}
+
+// Denylist is the store consulted by middleware.Auth
+var Denylist DenylistStore = NewMemoryDenylist()
+
+// InitDenylist selects the denylist store from TOKEN_DENYLIST_STORE (memory or postgres)
+func InitDenylist() {
+	if os.Getenv("TOKEN_DENYLIST_STORE") == "postgres" {
+		Denylist = NewPostgresDenylist()
+		return
+	}
+	Denylist = NewMemoryDenylist()
+}
+
+// StartDenylistPruner removes expired entries from the denylist periodically
+func StartDenylistPruner(interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for range ticker.C {
+			if err := Denylist.Prune(); err != nil {
+				log.Println("[Denylist] prune failed:", err)
+			}
+		}
+	}()
+}
+
+// DenyToken revokes an access token until its expiry
+func DenyToken(jti string, expiresAt time.Time) error {
+	if jti == "" || time.Now().After(expiresAt) {
+		return nil
+	}
+	return Denylist.Add(jti, expiresAt)
+}
+
+// ===================== MEMORY =====================
+
+type MemoryDenylist struct {
+	mu      sync.RWMutex
+	entries map[string]time.Time
+}
+
+func NewMemoryDenylist() *MemoryDenylist {
+	return &MemoryDenylist{entries: map[string]time.Time{}}
+}
+
+func (d *MemoryDenylist) Add(jti string, expiresAt time.Time) error {
+	d.mu.Lock()
+	d.entries[jti] = expiresAt
+	d.mu.Unlock()
+	return nil
+}
+
+func (d *MemoryDenylist) Contains(jti string) (bool, error) {
+	d.mu.RLock()
+	expiresAt, ok := d.entries[jti]
+	d.mu.RUnlock()
+	return ok && time.Now().Before(expiresAt), nil
+}
+
+// Len returns the number of entries, including expired ones not yet pruned
+func (d *MemoryDenylist) Len() int {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	return len(d.entries)
+}
+
+func (d *MemoryDenylist) Prune() error {
+	now := time.Now()
+	d.mu.Lock()
+	for jti, expiresAt := range d.entries {
+		if now.After(expiresAt) {
+			delete(d.entries, jti)
+		}
+	}
+	d.mu.Unlock()
+	return nil
+}
+
+// ===================== POSTGRES =====================
+
+type PostgresDenylist struct{}
+
+func NewPostgresDenylist() *PostgresDenylist {
+	return &PostgresDenylist{}
+}
+
+func (d *PostgresDenylist) Add(jti string, expiresAt time.Time) error {
+	return database.DB.Clauses(clause.OnConflict{DoNothing: true}).
+		Create(&model.DeniedToken{JTI: jti, ExpiresAt: expiresAt}).Error
+}
+
+func (d *PostgresDenylist) Contains(jti string) (bool, error) {
+	var count int64
+	err := database.DB.Model(&model.DeniedToken{}).
+		Where("jti = ? AND expires_at > ?", jti, time.Now()).
+		Count(&count).Error
+	return count > 0, err
+}
+
+func (d *PostgresDenylist) Prune() error {
+	return database.DB.Where("expires_at <= ?", time.Now()).Delete(&model.DeniedToken{}).Error
+}
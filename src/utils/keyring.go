@@ -0,0 +1,327 @@
+package utils
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"log"
+	"math/big"
+	"os"
+	"sync"
+	"time"
+
+	"go-journey/src/database"
+	"go-journey/src/model"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// keyringReloadInterval limits how often an unknown kid triggers a reload
+const keyringReloadInterval = 30 * time.Second
+
+type signingKey struct {
+	kid     string
+	method  jwt.SigningMethod
+	private crypto.PrivateKey
+	public  crypto.PublicKey
+}
+
+type keyringState struct {
+	mu       sync.RWMutex
+	keys     map[string]*signingKey
+	primary  *signingKey
+	loadedAt time.Time
+}
+
+var keyring = &keyringState{keys: map[string]*signingKey{}}
+
+// JWTAlgorithm returns the configured signing algorithm: HS256, RS256 or EdDSA
+func JWTAlgorithm() string {
+	switch alg := os.Getenv("JWT_ALG"); alg {
+	case "RS256", "EdDSA":
+		return alg
+	default:
+		return "HS256"
+	}
+}
+
+// IsAsymmetric reports whether tokens are signed with the keyring instead of JWT_SECRET
+func IsAsymmetric() bool {
+	return JWTAlgorithm() != "HS256"
+}
+
+// LoadKeyring reads the signing keys from the database. When asymmetric
+// signing is enabled and no active key exists yet, one is generated.
+func LoadKeyring() error {
+	if !IsAsymmetric() {
+		return nil
+	}
+
+	if err := reloadKeyring(); err != nil {
+		return err
+	}
+
+	keyring.mu.RLock()
+	hasPrimary := keyring.primary != nil
+	keyring.mu.RUnlock()
+	if hasPrimary {
+		return nil
+	}
+
+	kid, err := RotateSigningKey()
+	if err != nil {
+		return err
+	}
+	log.Printf("🔑 Generated initial %s signing key %s", JWTAlgorithm(), kid)
+	return nil
+}
+
+// StartKeyringRefresher periodically reloads the keyring so that keys rotated
+// by another process are picked up.
+func StartKeyringRefresher(interval time.Duration) {
+	if !IsAsymmetric() {
+		return
+	}
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for range ticker.C {
+			if err := reloadKeyring(); err != nil {
+				log.Println("[Keyring] reload failed:", err)
+			}
+		}
+	}()
+}
+
+// RotateSigningKey generates a new active key for the configured algorithm,
+// retires the previous active keys and removes retired keys whose tokens
+// have all expired.
+func RotateSigningKey() (string, error) {
+	if !IsAsymmetric() {
+		return "", errors.New("key rotation requires JWT_ALG RS256 or EdDSA")
+	}
+
+	record, err := GenerateSigningKey(JWTAlgorithm())
+	if err != nil {
+		return "", err
+	}
+
+	now := time.Now()
+	tx := database.DB.Begin()
+	if err := tx.Model(&model.SigningKey{}).
+		Where("status = ?", model.SigningKeyActive).
+		Updates(map[string]interface{}{"status": model.SigningKeyRetired, "retired_at": now}).Error; err != nil {
+		tx.Rollback()
+		return "", err
+	}
+	if err := tx.Create(&record).Error; err != nil {
+		tx.Rollback()
+		return "", err
+	}
+	if err := tx.Where("status = ? AND retired_at < ?", model.SigningKeyRetired, now.Add(-RefreshTokenTTL())).
+		Delete(&model.SigningKey{}).Error; err != nil {
+		tx.Rollback()
+		return "", err
+	}
+	if err := tx.Commit().Error; err != nil {
+		return "", err
+	}
+
+	return record.Kid, reloadKeyring()
+}
+
+// JWKS returns the public signing keys as a JSON Web Key Set
+func JWKS() map[string]interface{} {
+	keyring.mu.RLock()
+	defer keyring.mu.RUnlock()
+
+	keys := make([]map[string]interface{}, 0, len(keyring.keys))
+	for _, k := range keyring.keys {
+		jwk := map[string]interface{}{
+			"kid": k.kid,
+			"alg": k.method.Alg(),
+			"use": "sig",
+		}
+		switch pub := k.public.(type) {
+		case *rsa.PublicKey:
+			jwk["kty"] = "RSA"
+			jwk["n"] = base64.RawURLEncoding.EncodeToString(pub.N.Bytes())
+			jwk["e"] = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes())
+		case ed25519.PublicKey:
+			jwk["kty"] = "OKP"
+			jwk["crv"] = "Ed25519"
+			jwk["x"] = base64.RawURLEncoding.EncodeToString(pub)
+		}
+		keys = append(keys, jwk)
+	}
+	return map[string]interface{}{"keys": keys}
+}
+
+// signToken signs claims with JWT_SECRET or the primary keyring key
+func signToken(claims jwt.MapClaims) (string, error) {
+	if !IsAsymmetric() {
+		return jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(os.Getenv("JWT_SECRET")))
+	}
+
+	keyring.mu.RLock()
+	primary := keyring.primary
+	keyring.mu.RUnlock()
+	if primary == nil {
+		return "", errors.New("no active signing key")
+	}
+
+	token := jwt.NewWithClaims(primary.method, claims)
+	token.Header["kid"] = primary.kid
+	return token.SignedString(primary.private)
+}
+
+// verificationKey resolves the key for a parsed token and rejects any token
+// whose alg header does not match the configured algorithm.
+func verificationKey(t *jwt.Token) (interface{}, error) {
+	if !IsAsymmetric() {
+		if t.Method.Alg() != jwt.SigningMethodHS256.Alg() {
+			return nil, fmt.Errorf("unexpected signing method %s", t.Method.Alg())
+		}
+		return []byte(os.Getenv("JWT_SECRET")), nil
+	}
+
+	kid, _ := t.Header["kid"].(string)
+	key := lookupKey(kid)
+	if key == nil {
+		return nil, fmt.Errorf("unknown signing key %q", kid)
+	}
+	if t.Method.Alg() != key.method.Alg() {
+		return nil, fmt.Errorf("unexpected signing method %s", t.Method.Alg())
+	}
+	return key.public, nil
+}
+
+func lookupKey(kid string) *signingKey {
+	keyring.mu.RLock()
+	key, ok := keyring.keys[kid]
+	stale := time.Since(keyring.loadedAt) > keyringReloadInterval
+	keyring.mu.RUnlock()
+	if ok || kid == "" || !stale {
+		return key
+	}
+
+	if err := reloadKeyring(); err != nil {
+		log.Println("[Keyring] reload failed:", err)
+		return nil
+	}
+	keyring.mu.RLock()
+	defer keyring.mu.RUnlock()
+	return keyring.keys[kid]
+}
+
+func reloadKeyring() error {
+	var records []model.SigningKey
+	if err := database.DB.Order("created_at ASC").Find(&records).Error; err != nil {
+		return err
+	}
+	return UseSigningKeys(records)
+}
+
+// UseSigningKeys replaces the keyring with the given keys, oldest first. The
+// active key of the configured algorithm signs new tokens, all of them verify.
+// LoadKeyring uses the keys stored in the database.
+func UseSigningKeys(records []model.SigningKey) error {
+	keys := make(map[string]*signingKey, len(records))
+	var primary *signingKey
+	for _, r := range records {
+		key, err := parseSigningKey(r)
+		if err != nil {
+			return fmt.Errorf("signing key %s: %w", r.Kid, err)
+		}
+		keys[r.Kid] = key
+		if r.Status == model.SigningKeyActive && r.Algorithm == JWTAlgorithm() {
+			primary = key
+		}
+	}
+
+	keyring.mu.Lock()
+	keyring.keys = keys
+	keyring.primary = primary
+	keyring.loadedAt = time.Now()
+	keyring.mu.Unlock()
+	return nil
+}
+
+// GenerateSigningKey creates an active key pair for alg without storing it
+func GenerateSigningKey(alg string) (model.SigningKey, error) {
+	var private crypto.PrivateKey
+	var public crypto.PublicKey
+	switch alg {
+	case "RS256":
+		key, err := rsa.GenerateKey(rand.Reader, 2048)
+		if err != nil {
+			return model.SigningKey{}, err
+		}
+		private, public = key, &key.PublicKey
+	case "EdDSA":
+		pub, key, err := ed25519.GenerateKey(rand.Reader)
+		if err != nil {
+			return model.SigningKey{}, err
+		}
+		private, public = key, pub
+	default:
+		return model.SigningKey{}, fmt.Errorf("unsupported algorithm %s", alg)
+	}
+
+	privDER, err := x509.MarshalPKCS8PrivateKey(private)
+	if err != nil {
+		return model.SigningKey{}, err
+	}
+	pubDER, err := x509.MarshalPKIXPublicKey(public)
+	if err != nil {
+		return model.SigningKey{}, err
+	}
+
+	suffix := make([]byte, 4)
+	if _, err := rand.Read(suffix); err != nil {
+		return model.SigningKey{}, err
+	}
+
+	return model.SigningKey{
+		Kid:        time.Now().UTC().Format("20060102") + "-" + hex.EncodeToString(suffix),
+		Algorithm:  alg,
+		PrivateKey: string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: privDER})),
+		PublicKey:  string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: pubDER})),
+		Status:     model.SigningKeyActive,
+	}, nil
+}
+
+func parseSigningKey(r model.SigningKey) (*signingKey, error) {
+	method := jwt.GetSigningMethod(r.Algorithm)
+	if method == nil {
+		return nil, fmt.Errorf("unsupported algorithm %s", r.Algorithm)
+	}
+
+	privBlock, _ := pem.Decode([]byte(r.PrivateKey))
+	pubBlock, _ := pem.Decode([]byte(r.PublicKey))
+	if privBlock == nil || pubBlock == nil {
+		return nil, errors.New("invalid PEM data")
+	}
+	private, err := x509.ParsePKCS8PrivateKey(privBlock.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	public, err := x509.ParsePKIXPublicKey(pubBlock.Bytes)
+	if err != nil {
+		return nil, err
+	}
+
+	return &signingKey{
+		kid:     r.Kid,
+		method:  method,
+		private: private,
+		public:  public,
+	}, nil
+}
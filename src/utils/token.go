@@ -1,18 +1,28 @@
 package utils
 
 import (
+	"errors"
 	"os"
 	"time"
 
+	"go-journey/src/model"
+
+	"github.com/gofiber/fiber/v2"
 	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
 )
 
 type TokenPair struct {
 	AccessToken  string `json:"accessToken"`
 	RefreshToken string `json:"refreshToken"`
+
+	// AccessJTI and AccessExpiresAt identify the access token for revocation
+	AccessJTI       string    `json:"-"`
+	AccessExpiresAt time.Time `json:"-"`
 }
 
-func ttlFromEnv(key string, def time.Duration) time.Duration {
+// TTLFromEnv parses a duration from an environment variable, falling back to def
+func TTLFromEnv(key string, def time.Duration) time.Duration {
 	if v := os.Getenv(key); v != "" {
 		if d, err := time.ParseDuration(v); err == nil {
 			return d
@@ -21,46 +31,77 @@ func ttlFromEnv(key string, def time.Duration) time.Duration {
 	return def
 }
 
-func GenerateTokenPair(userID string) (TokenPair, error) {
-	secret := os.Getenv("JWT_SECRET")
-	accessTTL := ttlFromEnv("ACCESS_TOKEN_TTL", 15*time.Minute)
-	refreshTTL := ttlFromEnv("REFRESH_TOKEN_TTL", 7*24*time.Hour)
+// AccessTokenTTL is the lifetime of an access token
+func AccessTokenTTL() time.Duration {
+	return TTLFromEnv("ACCESS_TOKEN_TTL", 15*time.Minute)
+}
+
+// RefreshTokenTTL is the lifetime of a refresh token and therefore of a session.
+func RefreshTokenTTL() time.Duration {
+	return TTLFromEnv("REFRESH_TOKEN_TTL", 7*24*time.Hour)
+}
+
+// GenerateTokenPair issues the tokens of a session. The access token carries
+// the user's role and token version so requests can be authorized without a
+// database lookup, and the session's login time as auth_time when known.
+// Tokens of a session created for an OpenID Connect client name the client
+// in "aud", which keeps them out of the API (see middleware.Auth).
+func GenerateTokenPair(user *model.User, session *model.Session) (TokenPair, error) {
+	accessTTL := AccessTokenTTL()
+	refreshTTL := RefreshTokenTTL()
 
 	now := time.Now()
+	accessJTI := uuid.New().String()
+	accessExp := now.Add(accessTTL)
 
-	access := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
-		"sub":  userID,
+	accessClaims := jwt.MapClaims{
+		"sub":  user.ID,
+		"sid":  session.ID,
+		"jti":  accessJTI,
+		"role": user.Role,
+		"ver":  user.TokenVersion,
 		"type": "access",
-		"exp":  now.Add(accessTTL).Unix(),
+		"exp":  accessExp.Unix(),
 		"iat":  now.Unix(),
-	})
+	}
+	if session.AuthenticatedAt != nil {
+		accessClaims["auth_time"] = session.AuthenticatedAt.Unix()
+	}
+	if session.ClientID != "" {
+		accessClaims["aud"] = session.ClientID
+	}
+	accessStr, err := signToken(accessClaims)
+	if err != nil {
+		return TokenPair{}, err
+	}
 
 	// Refresh token
-	refresh := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
-		"sub":  userID,
+	refreshClaims := jwt.MapClaims{
+		"sub":  user.ID,
+		"sid":  session.ID,
+		"jti":  uuid.New().String(),
 		"type": "refresh",
 		"exp":  now.Add(refreshTTL).Unix(),
 		"iat":  now.Unix(),
-	})
-
-	// Sign token
-	accessStr, err := access.SignedString([]byte(secret))
-	if err != nil {
-		return TokenPair{}, err
 	}
-	refreshStr, err := refresh.SignedString([]byte(secret))
+	if session.ClientID != "" {
+		refreshClaims["aud"] = session.ClientID
+	}
+	refreshStr, err := signToken(refreshClaims)
 	if err != nil {
 		return TokenPair{}, err
 	}
 
-	return TokenPair{AccessToken: accessStr, RefreshToken: refreshStr}, nil
+	return TokenPair{
+		AccessToken:     accessStr,
+		RefreshToken:    refreshStr,
+		AccessJTI:       accessJTI,
+		AccessExpiresAt: accessExp,
+	}, nil
 }
 
 func ParseToken(tokenStr string) (*jwt.Token, jwt.MapClaims, error) {
-	secret := os.Getenv("JWT_SECRET")
-	t, err := jwt.Parse(tokenStr, func(t *jwt.Token) (interface{}, error) {
-		return []byte(secret), nil
-	})
+	t, err := jwt.Parse(tokenStr, verificationKey)
 	if err != nil {
 		return nil, nil, err
 	}
@@ -72,3 +113,110 @@ func ParseToken(tokenStr string) (*jwt.Token, jwt.MapClaims, error) {
 
 	return t, claims, nil
 }
+
+// AccessTokenRevoked reports whether a validly signed access token was
+// denylisted or predates a bump of its user's (or impersonating actor's)
+// token version.
+func AccessTokenRevoked(claims jwt.MapClaims) bool {
+	jti, _ := claims["jti"].(string)
+	if denied, err := Denylist.Contains(jti); jti == "" || err != nil || denied {
+		return true
+	}
+
+	sub, _ := claims["sub"].(string)
+	ver, _ := claims["ver"].(float64)
+	if current, err := CurrentTokenVersion(sub); err != nil || int(ver) != current {
+		return true
+	}
+
+	if act, ok := claims["act"].(map[string]interface{}); ok {
+		actorID, _ := act["sub"].(string)
+		actorVer, _ := act["ver"].(float64)
+		current, err := CurrentTokenVersion(actorID)
+		if actorID == "" || err != nil || int(actorVer) != current {
+			return true
+		}
+	}
+	return false
+}
+
+// RecentlyAuthenticated reports whether the access token of the request
+// carries an auth_time within maxAge (see middleware.RequireRecentAuth)
+func RecentlyAuthenticated(c *fiber.Ctx, maxAge time.Duration) bool {
+	authTime, ok := c.Locals("authTime").(time.Time)
+	return ok && time.Since(authTime) <= maxAge
+}
+
+// ElevatedTokenTTL is the lifetime of the token issued by /auth/reauthenticate
+func ElevatedTokenTTL() time.Duration {
+	return TTLFromEnv("REAUTH_TOKEN_TTL", 5*time.Minute)
+}
+
+// GenerateElevatedToken issues a short-lived access token for a session with
+// auth_time set to now, after the user confirmed their credentials again.
+func GenerateElevatedToken(user *model.User, sessionID string) (string, string, time.Time, error) {
+	now := time.Now()
+	jti := uuid.New().String()
+	exp := now.Add(ElevatedTokenTTL())
+
+	token, err := signToken(jwt.MapClaims{
+		"sub":       user.ID,
+		"sid":       sessionID,
+		"jti":       jti,
+		"role":      user.Role,
+		"ver":       user.TokenVersion,
+		"type":      "access",
+		"auth_time": now.Unix(),
+		"exp":       exp.Unix(),
+		"iat":       now.Unix(),
+	})
+	return token, jti, exp, err
+}
+
+// GenerateImpersonationToken issues a short-lived access token for user on
+// behalf of actor. The RFC 8693 "act" claim names the actor, and its token
+// version so that the token dies with the actor's own tokens. It belongs to
+// no session and cannot be refreshed.
+func GenerateImpersonationToken(user *model.User, actor *model.User) (string, string, time.Time, error) {
+	now := time.Now()
+	jti := uuid.New().String()
+	exp := now.Add(TTLFromEnv("IMPERSONATION_TOKEN_TTL", 15*time.Minute))
+
+	token, err := signToken(jwt.MapClaims{
+		"sub":  user.ID,
+		"jti":  jti,
+		"role": user.Role,
+		"ver":  user.TokenVersion,
+		"act": map[string]interface{}{
+			"sub": actor.ID,
+			"ver": actor.TokenVersion,
+		},
+		"type": "access",
+		"exp":  exp.Unix(),
+		"iat":  now.Unix(),
+	})
+	return token, jti, exp, err
+}
+
+// SignIDToken signs the claims of an OpenID Connect ID token. Client
+// applications verify ID tokens with the JWKS, so an asymmetric JWT_ALG is required.
+func SignIDToken(claims map[string]interface{}) (string, error) {
+	if !IsAsymmetric() {
+		return "", errors.New("ID tokens require JWT_ALG RS256 or EdDSA")
+	}
+	return signToken(jwt.MapClaims(claims))
+}
+
+// GenerateMFAToken issues the short-lived token returned by a password login
+// when the user has two-factor authentication enabled. It only grants access
+// to the second login step.
+func GenerateMFAToken(user *model.User) (string, error) {
+	now := time.Now()
+	return signToken(jwt.MapClaims{
+		"sub":  user.ID,
+		"jti":  uuid.New().String(),
+		"type": "mfa_pending",
+		"exp":  now.Add(TTLFromEnv("MFA_TOKEN_TTL", 5*time.Minute)).Unix(),
+		"iat":  now.Unix(),
+	})
+}
@@ -0,0 +1,84 @@
+package utils
+
+import (
+	"sync"
+	"time"
+
+	"go-journey/src/database"
+	"go-journey/src/model"
+
+	"gorm.io/gorm"
+)
+
+type tokenVersionEntry struct {
+	version  int
+	loadedAt time.Time
+}
+
+var tokenVersions = struct {
+	sync.RWMutex
+	entries map[string]tokenVersionEntry
+}{entries: map[string]tokenVersionEntry{}}
+
+func tokenVersionTTL() time.Duration {
+	return TTLFromEnv("TOKEN_VERSION_CACHE_TTL", 30*time.Second)
+}
+
+// CurrentTokenVersion returns the user's token version, cached for
+// TOKEN_VERSION_CACHE_TTL so that most requests need no query.
+func CurrentTokenVersion(userID string) (int, error) {
+	ttl := tokenVersionTTL()
+
+	tokenVersions.RLock()
+	entry, ok := tokenVersions.entries[userID]
+	tokenVersions.RUnlock()
+	if ok && time.Since(entry.loadedAt) < ttl {
+		return entry.version, nil
+	}
+
+	var user model.User
+	if err := database.DB.Select("token_version").Where("id = ?", userID).First(&user).Error; err != nil {
+		return 0, err
+	}
+
+	tokenVersions.Lock()
+	tokenVersions.entries[userID] = tokenVersionEntry{version: user.TokenVersion, loadedAt: time.Now()}
+	tokenVersions.Unlock()
+	return user.TokenVersion, nil
+}
+
+// BumpTokenVersion invalidates every access token issued to the user so far
+func BumpTokenVersion(userID string) error {
+	err := database.DB.Unscoped().Model(&model.User{}).
+		Where("id = ?", userID).
+		Update("token_version", gorm.Expr("token_version + 1")).Error
+
+	tokenVersions.Lock()
+	delete(tokenVersions.entries, userID)
+	tokenVersions.Unlock()
+	return err
+}
+
+// PruneTokenVersions drops cached versions older than the cache TTL
+func PruneTokenVersions() {
+	ttl := tokenVersionTTL()
+	tokenVersions.Lock()
+	for userID, entry := range tokenVersions.entries {
+		if time.Since(entry.loadedAt) >= ttl {
+			delete(tokenVersions.entries, userID)
+		}
+	}
+	tokenVersions.Unlock()
+}
+
+// StartTokenVersionPruner removes stale cache entries periodically so the
+// cache does not grow with every user that ever made a request
+func StartTokenVersionPruner(interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for range ticker.C {
+			PruneTokenVersions()
+		}
+	}()
+}
@@ -0,0 +1,84 @@
+package utils
+
+import (
+	"html"
+	"strings"
+	"unicode"
+)
+
+// Trigrams splits text into the trigram set pg_trgm uses: lowercase words of
+// letters and digits, each padded with two spaces in front and one behind.
+func Trigrams(text string) map[string]struct{} {
+	set := map[string]struct{}{}
+	for _, word := range searchWords(text) {
+		padded := []rune("  " + word + " ")
+		for i := 0; i+3 <= len(padded); i++ {
+			set[string(padded[i:i+3])] = struct{}{}
+		}
+	}
+	return set
+}
+
+// TrigramSimilarity mirrors pg_trgm's similarity(): shared trigrams divided
+// by all distinct trigrams of both texts, from 0 to 1.
+func TrigramSimilarity(a string, b string) float64 {
+	ta, tb := Trigrams(a), Trigrams(b)
+	if len(ta) == 0 || len(tb) == 0 {
+		return 0
+	}
+	shared := 0
+	for trigram := range ta {
+		if _, ok := tb[trigram]; ok {
+			shared++
+		}
+	}
+	return float64(shared) / float64(len(ta)+len(tb)-shared)
+}
+
+// HighlightMatches HTML-escapes text and wraps every word that contains a
+// query word, or is similar to one, in <mark> tags.
+func HighlightMatches(text string, query string, threshold float64) string {
+	terms := searchWords(query)
+
+	var out strings.Builder
+	runes := []rune(text)
+	for i := 0; i < len(runes); {
+		j := i
+		for j < len(runes) && isWordRune(runes[j]) {
+			j++
+		}
+		if j == i {
+			out.WriteString(html.EscapeString(string(runes[i])))
+			i++
+			continue
+		}
+
+		word := string(runes[i:j])
+		if matchesTerm(strings.ToLower(word), terms, threshold) {
+			out.WriteString("<mark>" + html.EscapeString(word) + "</mark>")
+		} else {
+			out.WriteString(html.EscapeString(word))
+		}
+		i = j
+	}
+	return out.String()
+}
+
+func matchesTerm(word string, terms []string, threshold float64) bool {
+	for _, term := range terms {
+		if strings.Contains(word, term) || TrigramSimilarity(word, term) >= threshold {
+			return true
+		}
+	}
+	return false
+}
+
+func searchWords(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !isWordRune(r)
+	})
+}
+
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r)
+}
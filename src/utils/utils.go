@@ -1,27 +1,107 @@
 package utils
 
 import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"os"
+	"strconv"
+	"time"
+
 	"go-journey/src/database"
 	"go-journey/src/model"
 )
 
-func SaveRefreshToken(userID string, refreshToken string) error {
-	return database.DB.Model(&model.User{}).
-		Where("id = ?", userID).
-		Update("refresh_token", refreshToken).Error
+// RefreshTokenStatus is the outcome of checking a presented refresh token
+type RefreshTokenStatus int
+
+const (
+	RefreshTokenInvalid RefreshTokenStatus = iota
+	RefreshTokenValid
+	RefreshTokenReused
+)
+
+// HashToken returns the hex encoded SHA-256 of a token, used for storage at rest
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// RandomToken returns n random bytes encoded as URL-safe base64
+func RandomToken(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// IntFromEnv parses an integer from an environment variable, falling back to def
+func IntFromEnv(key string, def int) int {
+	if v := os.Getenv(key); v != "" {
+		if n, err := strconv.Atoi(v); err == nil {
+			return n
+		}
+	}
+	return def
+}
+
+// SaveRefreshToken stores the new refresh token of a session and remembers
+// the access token issued with it, so that it can be denylisted later.
+func SaveRefreshToken(userID string, sessionID string, tokens TokenPair) error {
+	now := time.Now()
+	if err := database.DB.Create(&model.RefreshToken{
+		SessionID: sessionID,
+		UserID:    userID,
+		TokenHash: HashToken(tokens.RefreshToken),
+		ExpiresAt: now.Add(RefreshTokenTTL()),
+	}).Error; err != nil {
+		return err
+	}
+
+	return database.DB.Model(&model.Session{}).
+		Where("id = ?", sessionID).
+		Updates(map[string]interface{}{
+			"access_jti":        tokens.AccessJTI,
+			"access_expires_at": tokens.AccessExpiresAt,
+			"last_used_at":      now,
+		}).Error
 }
 
-func IsRefreshTokenValid(userID string, refreshToken string) bool {
-	var user model.User
-	if err := database.DB.Select("refresh_token").
-		Where("id = ?", userID).First(&user).Error; err != nil {
-		return false
+// CheckRefreshToken reports whether a refresh token is the live member of its
+// family, an already rotated (reused) member, or otherwise invalid.
+func CheckRefreshToken(sessionID string, refreshToken string) RefreshTokenStatus {
+	var token model.RefreshToken
+	if err := database.DB.Where("token_hash = ? AND session_id = ?", HashToken(refreshToken), sessionID).
+		First(&token).Error; err != nil {
+		return RefreshTokenInvalid
+	}
+	if token.RotatedAt != nil {
+		return RefreshTokenReused
+	}
+
+	var session model.Session
+	if err := database.DB.Select("expires_at", "revoked_at").
+		Where("id = ?", sessionID).First(&session).Error; err != nil {
+		return RefreshTokenInvalid
+	}
+	now := time.Now()
+	if session.RevokedAt != nil || now.After(session.ExpiresAt) || now.After(token.ExpiresAt) {
+		return RefreshTokenInvalid
 	}
-	return user.RefreshToken == refreshToken
+	return RefreshTokenValid
+}
+
+func IsRefreshTokenValid(sessionID string, refreshToken string) bool {
+	return CheckRefreshToken(sessionID, refreshToken) == RefreshTokenValid
 }
 
-func RevokeRefreshToken(userID string) error {
-	return database.DB.Model(&model.User{}).
-		Where("id = ?", userID).
-		Update("refresh_token", "").Error
+// ConsumeRefreshToken marks a refresh token as rotated. It returns false when
+// another request rotated the token first.
+func ConsumeRefreshToken(sessionID string, refreshToken string) (bool, error) {
+	result := database.DB.Model(&model.RefreshToken{}).
+		Where("token_hash = ? AND session_id = ? AND rotated_at IS NULL", HashToken(refreshToken), sessionID).
+		Update("rotated_at", time.Now())
+	return result.RowsAffected == 1, result.Error
 }
@@ -0,0 +1,7 @@
+package validation
+
+type CreateAPIKeyRequest struct {
+	Name          string   `json:"name" validate:"required,max=100"`
+	Scopes        []string `json:"scopes"`
+	ExpiresInDays int      `json:"expires_in_days" validate:"omitempty,min=1,max=3650"`
+}
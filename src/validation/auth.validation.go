@@ -1,17 +1,72 @@
 package validation
 
 type RegisterRequest struct {
-	Username string `json:"username" validate:"required" message:"Username is required"`
-	FullName string `json:"full_name" validate:"required" message:"Full name is required"`
-	Password string `json:"password" validate:"required,min=6" message:"Password is required and must be at least 6 characters"`
-	Role     string `json:"role"`
+	Username   string `json:"username" validate:"required" message:"Username is required"`
+	Email      string `json:"email" validate:"required,email" message:"A valid email is required"`
+	FullName   string `json:"full_name" validate:"required" message:"Full name is required"`
+	Password   string `json:"password" validate:"required" message:"Password is required"`
+	Role       string `json:"role"`
+	InviteCode string `json:"invite_code"`
+	DeviceName string `json:"device_name"`
 }
 
 type LoginRequest struct {
-	Username string `json:"username" validate:"required" message:"Username is required"`
-	Password string `json:"password" validate:"required" message:"Password is required"`
+	Username   string `json:"username" validate:"required" message:"Username is required"`
+	Password   string `json:"password" validate:"required" message:"Password is required"`
+	DeviceName string `json:"device_name"`
 }
 
 type RefreshRequest struct {
 	RefreshToken string `json:"refreshToken" validate:"required" message:"Refresh token is required"`
 }
+
+type ForgotPasswordRequest struct {
+	Username string `json:"username" validate:"required" message:"Username or email is required"`
+}
+
+type ResetPasswordRequest struct {
+	Token    string `json:"token" validate:"required" message:"Reset token is required"`
+	Password string `json:"password" validate:"required" message:"Password is required"`
+}
+
+type VerifyEmailRequest struct {
+	Token string `json:"token" validate:"required" message:"Verification token is required"`
+}
+
+type ResendVerificationRequest struct {
+	Email string `json:"email" validate:"required,email" message:"A valid email is required"`
+}
+
+type LoginMFARequest struct {
+	MFAToken   string `json:"mfa_token" validate:"required" message:"MFA token is required"`
+	Code       string `json:"code" validate:"required" message:"Code is required"`
+	DeviceName string `json:"device_name"`
+}
+
+type TOTPCodeRequest struct {
+	Code string `json:"code" validate:"required" message:"Code is required"`
+}
+
+type ReauthenticateRequest struct {
+	Password string `json:"password" validate:"required_without=Code" message:"Password or code is required"`
+	Code     string `json:"code" validate:"required_without=Password" message:"Password or code is required"`
+}
+
+type DisableTOTPRequest struct {
+	Password string `json:"password" validate:"required" message:"Password is required"`
+	Code     string `json:"code" validate:"required" message:"Code is required"`
+}
+
+type MagicLinkRequest struct {
+	Email string `json:"email" validate:"required,email" message:"A valid email is required"`
+}
+
+type VerifyMagicLinkRequest struct {
+	Token       string `json:"token" validate:"required" message:"Login token is required"`
+	DeviceToken string `json:"device_token" validate:"required" message:"Device token is required"`
+	DeviceName  string `json:"device_name"`
+}
+
+type MagicLinkSettingsRequest struct {
+	Enabled *bool `json:"enabled" validate:"required" message:"Enabled is required"`
+}
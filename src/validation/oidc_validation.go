@@ -0,0 +1,42 @@
+package validation
+
+type AuthorizeRequest struct {
+	ResponseType        string `query:"response_type" json:"response_type"`
+	ClientID            string `query:"client_id" json:"client_id"`
+	RedirectURI         string `query:"redirect_uri" json:"redirect_uri"`
+	Scope               string `query:"scope" json:"scope"`
+	State               string `query:"state" json:"state"`
+	Nonce               string `query:"nonce" json:"nonce"`
+	CodeChallenge       string `query:"code_challenge" json:"code_challenge"`
+	CodeChallengeMethod string `query:"code_challenge_method" json:"code_challenge_method"`
+}
+
+type TokenRequest struct {
+	GrantType    string `form:"grant_type" json:"grant_type"`
+	Code         string `form:"code" json:"code"`
+	RedirectURI  string `form:"redirect_uri" json:"redirect_uri"`
+	CodeVerifier string `form:"code_verifier" json:"code_verifier"`
+	RefreshToken string `form:"refresh_token" json:"refresh_token"`
+	ClientID     string `form:"client_id" json:"client_id"`
+	ClientSecret string `form:"client_secret" json:"client_secret"`
+}
+
+type CreateOAuthClientRequest struct {
+	Name         string   `json:"name" validate:"required,max=100"`
+	RedirectURIs []string `json:"redirect_uris" validate:"required,min=1,dive,url"`
+	Confidential bool     `json:"confidential"`
+}
+
+type IntrospectRequest struct {
+	Token         string `form:"token" json:"token"`
+	TokenTypeHint string `form:"token_type_hint" json:"token_type_hint"`
+	ClientID      string `form:"client_id" json:"client_id"`
+	ClientSecret  string `form:"client_secret" json:"client_secret"`
+}
+
+type RevokeRequest struct {
+	Token         string `form:"token" json:"token"`
+	TokenTypeHint string `form:"token_type_hint" json:"token_type_hint"`
+	ClientID      string `form:"client_id" json:"client_id"`
+	ClientSecret  string `form:"client_secret" json:"client_secret"`
+}
@@ -0,0 +1,20 @@
+package validation
+
+type CreateRoleRequest struct {
+	Name        string   `json:"name" validate:"required,min=2,max=20"`
+	Description string   `json:"description" validate:"omitempty,max=255"`
+	Permissions []string `json:"permissions"`
+}
+
+type UpdateRoleRequest struct {
+	Description string `json:"description" validate:"omitempty,max=255"`
+}
+
+type SetRolePermissionsRequest struct {
+	Permissions []string `json:"permissions" validate:"required"`
+}
+
+type CreatePermissionRequest struct {
+	Name        string `json:"name" validate:"required,max=100"`
+	Description string `json:"description" validate:"omitempty,max=255"`
+}
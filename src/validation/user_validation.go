@@ -7,8 +7,9 @@ import (
 // ===================== STRUCT =====================
 type CreateUserRequest struct {
 	Username      string `json:"username" validate:"required,min=3"`
+	Email         string `json:"email" validate:"required,email"`
 	FullName      string `json:"fullName" validate:"required,min=3"`
-	Password      string `json:"password" validate:"required,min=6"`
+	Password      string `json:"password" validate:"required"`
 	Role          string `json:"role"`
 	EsignID       string `json:"esignId"`
 	EsignStatusID string `json:"esignStatusId"`
@@ -17,13 +18,55 @@ type CreateUserRequest struct {
 
 type UpdateUserRequest struct {
 	Username      string `json:"username" validate:"omitempty,min=3"`
+	Email         string `json:"email" validate:"omitempty,email"`
 	FullName      string `json:"fullName" validate:"omitempty,min=3"`
-	Password      string `json:"password" validate:"omitempty,min=6"`
+	Password      string `json:"password"`
 	Role          string `json:"role" validate:"omitempty"`
 	EsignID       string `json:"esignId" validate:"omitempty"`
 	EsignStatusID string `json:"esignStatusId" validate:"omitempty"`
 }
 
+// ListUsersRequest is the query string of GET /users
+type ListUsersRequest struct {
+	Page           int    `query:"page" validate:"omitempty,min=1"`
+	PerPage        int    `query:"per_page" validate:"omitempty,min=1,max=100"`
+	Cursor         string `query:"cursor"`
+	Sort           string `query:"sort"`
+	Query          string `query:"q" validate:"omitempty,max=100"`
+	Role           string `query:"role"`
+	Status         string `query:"status" validate:"omitempty,oneof=active disabled pending"`
+	EsignStatusID  string `query:"esign_status_id"`
+	RegisteredFrom string `query:"registered_from" validate:"omitempty,datetime=2006-01-02"`
+	RegisteredTo   string `query:"registered_to" validate:"omitempty,datetime=2006-01-02"`
+}
+
+// SearchUsersRequest is the query string of GET /users/search
+type SearchUsersRequest struct {
+	Query string `query:"q" validate:"required,min=2,max=100"`
+	Limit int    `query:"limit" validate:"omitempty,min=1,max=50"`
+}
+
+type ImpersonateRequest struct {
+	Reason string `json:"reason" validate:"omitempty,max=255"`
+}
+
+type CreateInvitationRequest struct {
+	Email          string `json:"email" validate:"omitempty,email"`
+	Role           string `json:"role" validate:"required"`
+	ExpiresInHours int    `json:"expires_in_hours" validate:"omitempty,min=1,max=720"`
+}
+
+type UpdateProfileRequest struct {
+	Username string `json:"username" validate:"omitempty,min=3,max=100"`
+	Email    string `json:"email" validate:"omitempty,email"`
+	FullName string `json:"fullName" validate:"omitempty,min=3,max=150"`
+}
+
+type ChangePasswordRequest struct {
+	CurrentPassword string `json:"currentPassword" validate:"required"`
+	NewPassword     string `json:"newPassword" validate:"required"`
+}
+
 // ===================== VALIDATION =====================
 var validate = validator.New()
 
@@ -31,14 +74,80 @@ var validate = validator.New()
 var customMessages = map[string]string{
 	"CreateUserRequest.Username.required": "Username wajib diisi",
 	"CreateUserRequest.Username.min":      "Username minimal 3 karakter",
+	"CreateUserRequest.Email.required":    "Email wajib diisi",
+	"CreateUserRequest.Email.email":       "Format email tidak valid",
 	"CreateUserRequest.FullName.required": "Nama lengkap wajib diisi",
 	"CreateUserRequest.FullName.min":      "Nama lengkap minimal 3 karakter",
 	"CreateUserRequest.Password.required": "Password wajib diisi",
-	"CreateUserRequest.Password.min":      "Password minimal 6 karakter",
 
 	"UpdateUserRequest.Username.min": "Username minimal 3 karakter",
+	"UpdateUserRequest.Email.email":  "Format email tidak valid",
 	"UpdateUserRequest.FullName.min": "Nama lengkap minimal 3 karakter",
-	"UpdateUserRequest.Password.min": "Password minimal 6 karakter",
+
+	"ListUsersRequest.Page.min":                "Halaman minimal 1",
+	"ListUsersRequest.PerPage.min":             "Jumlah per halaman minimal 1",
+	"ListUsersRequest.PerPage.max":             "Jumlah per halaman maksimal 100",
+	"ListUsersRequest.Query.max":               "Kata kunci pencarian maksimal 100 karakter",
+	"ListUsersRequest.Status.oneof":            "Status harus active, disabled, atau pending",
+	"ListUsersRequest.RegisteredFrom.datetime": "Format tanggal registered_from harus YYYY-MM-DD",
+	"ListUsersRequest.RegisteredTo.datetime":   "Format tanggal registered_to harus YYYY-MM-DD",
+
+	"SearchUsersRequest.Query.required": "Kata kunci pencarian wajib diisi",
+	"SearchUsersRequest.Query.min":      "Kata kunci pencarian minimal 2 karakter",
+	"SearchUsersRequest.Query.max":      "Kata kunci pencarian maksimal 100 karakter",
+	"SearchUsersRequest.Limit.min":      "Limit minimal 1",
+	"SearchUsersRequest.Limit.max":      "Limit maksimal 50",
+
+	"ImpersonateRequest.Reason.max": "Alasan maksimal 255 karakter",
+
+	"CreateInvitationRequest.Email.email":        "Format email tidak valid",
+	"CreateInvitationRequest.Role.required":      "Role wajib diisi",
+	"CreateInvitationRequest.ExpiresInHours.min": "Masa berlaku minimal 1 jam",
+	"CreateInvitationRequest.ExpiresInHours.max": "Masa berlaku maksimal 720 jam",
+
+	"UpdateProfileRequest.Username.min":              "Username minimal 3 karakter",
+	"UpdateProfileRequest.Username.max":              "Username maksimal 100 karakter",
+	"UpdateProfileRequest.Email.email":               "Format email tidak valid",
+	"UpdateProfileRequest.FullName.min":              "Nama lengkap minimal 3 karakter",
+	"UpdateProfileRequest.FullName.max":              "Nama lengkap maksimal 150 karakter",
+	"ChangePasswordRequest.CurrentPassword.required": "Password saat ini wajib diisi",
+	"ChangePasswordRequest.NewPassword.required":     "Password baru wajib diisi",
+
+	"RegisterRequest.Email.required":           "Email wajib diisi",
+	"RegisterRequest.Email.email":              "Format email tidak valid",
+	"ResendVerificationRequest.Email.required": "Email wajib diisi",
+	"ResendVerificationRequest.Email.email":    "Format email tidak valid",
+
+	"LoginMFARequest.MFAToken.required":               "MFA token wajib diisi",
+	"LoginMFARequest.Code.required":                   "Kode autentikasi wajib diisi",
+	"TOTPCodeRequest.Code.required":                   "Kode autentikasi wajib diisi",
+	"DisableTOTPRequest.Password.required":            "Password wajib diisi",
+	"ReauthenticateRequest.Password.required_without": "Password atau kode autentikasi wajib diisi",
+	"ReauthenticateRequest.Code.required_without":     "Password atau kode autentikasi wajib diisi",
+	"DisableTOTPRequest.Code.required":                "Kode autentikasi wajib diisi",
+
+	"MagicLinkRequest.Email.required":             "Email wajib diisi",
+	"MagicLinkRequest.Email.email":                "Format email tidak valid",
+	"VerifyMagicLinkRequest.Token.required":       "Token login wajib diisi",
+	"VerifyMagicLinkRequest.DeviceToken.required": "Device token wajib diisi",
+	"MagicLinkSettingsRequest.Enabled.required":   "Status aktif wajib diisi",
+
+	"CreateRoleRequest.Name.required":                "Nama role wajib diisi",
+	"CreateRoleRequest.Name.min":                     "Nama role minimal 2 karakter",
+	"CreateRoleRequest.Name.max":                     "Nama role maksimal 20 karakter",
+	"SetRolePermissionsRequest.Permissions.required": "Daftar permission wajib diisi",
+	"CreatePermissionRequest.Name.required":          "Nama permission wajib diisi",
+	"CreatePermissionRequest.Name.max":               "Nama permission maksimal 100 karakter",
+
+	"CreateOAuthClientRequest.Name.required":         "Nama aplikasi wajib diisi",
+	"CreateOAuthClientRequest.Name.max":              "Nama aplikasi maksimal 100 karakter",
+	"CreateOAuthClientRequest.RedirectURIs.required": "Redirect URI wajib diisi",
+	"CreateOAuthClientRequest.RedirectURIs.min":      "Minimal satu redirect URI",
+
+	"CreateAPIKeyRequest.Name.required":     "Nama API key wajib diisi",
+	"CreateAPIKeyRequest.Name.max":          "Nama API key maksimal 100 karakter",
+	"CreateAPIKeyRequest.ExpiresInDays.min": "Masa berlaku minimal 1 hari",
+	"CreateAPIKeyRequest.ExpiresInDays.max": "Masa berlaku maksimal 3650 hari",
 }
 
 // ValidateStruct memvalidasi struct dan mengembalikan error pertama
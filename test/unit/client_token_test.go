@@ -0,0 +1,41 @@
+package unit
+
+import (
+	"io"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"go-journey/src/middleware"
+	"go-journey/src/model"
+	"go-journey/src/utils"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func TestAuthRejectsClientAccessTokens(t *testing.T) {
+	t.Setenv("JWT_SECRET", "test-secret")
+	t.Setenv("JWT_ALG", "")
+
+	user := model.User{ID: "user-1", Role: model.RoleAdmin}
+	tokens, err := utils.GenerateTokenPair(&user, &model.Session{ID: "session-1", ClientID: "spa"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	app := fiber.New()
+	app.Get("/", middleware.Auth(), func(c *fiber.Ctx) error {
+		return c.SendStatus(fiber.StatusOK)
+	})
+
+	req := httptest.NewRequest(fiber.MethodGet, "/", nil)
+	req.Header.Set("Authorization", "Bearer "+tokens.AccessToken)
+	resp, err := app.Test(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode != fiber.StatusUnauthorized || !strings.Contains(string(body), "client application") {
+		t.Fatalf("status %d (%s), want a rejected client token", resp.StatusCode, body)
+	}
+}
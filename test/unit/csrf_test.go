@@ -0,0 +1,82 @@
+package unit
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"go-journey/src/utils"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func TestValidCSRFRequiresTheSessionToken(t *testing.T) {
+	t.Setenv("CSRF_SECRET", "test-secret")
+
+	app := fiber.New()
+	app.Post("/", func(c *fiber.Ctx) error {
+		if !utils.ValidCSRF(c, "session-1") {
+			return c.SendStatus(fiber.StatusForbidden)
+		}
+		return c.SendStatus(fiber.StatusOK)
+	})
+
+	cases := []struct {
+		name   string
+		cookie string
+		header string
+		want   int
+	}{
+		{"session token", "", utils.CSRFToken("session-1"), fiber.StatusOK},
+		{"other session", "", utils.CSRFToken("session-2"), fiber.StatusForbidden},
+		{"tossed cookie echoed in header", "abc123", "abc123", fiber.StatusForbidden},
+		{"missing header", utils.CSRFToken("session-1"), "", fiber.StatusForbidden},
+	}
+	for _, tc := range cases {
+		req := httptest.NewRequest(fiber.MethodPost, "/", nil)
+		if tc.cookie != "" {
+			req.Header.Set("Cookie", utils.CSRFCookie+"="+tc.cookie)
+		}
+		if tc.header != "" {
+			req.Header.Set(utils.CSRFHeader, tc.header)
+		}
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.StatusCode != tc.want {
+			t.Errorf("%s: status %d, want %d", tc.name, resp.StatusCode, tc.want)
+		}
+	}
+}
+
+func TestValidCSRFRejectsTokensWithoutAKey(t *testing.T) {
+	t.Setenv("CSRF_SECRET", "")
+	t.Setenv("JWT_SECRET", "")
+	if utils.CSRFSecretConfigured() {
+		t.Fatal("expected no CSRF key to be configured")
+	}
+
+	app := fiber.New()
+	app.Post("/", func(c *fiber.Ctx) error {
+		if !utils.ValidCSRF(c, "session-1") {
+			return c.SendStatus(fiber.StatusForbidden)
+		}
+		return c.SendStatus(fiber.StatusOK)
+	})
+
+	// Anybody can compute the HMAC with an empty key
+	req := httptest.NewRequest(fiber.MethodPost, "/", nil)
+	req.Header.Set(utils.CSRFHeader, utils.CSRFToken("session-1"))
+	resp, err := app.Test(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != fiber.StatusForbidden {
+		t.Errorf("status %d, want %d", resp.StatusCode, fiber.StatusForbidden)
+	}
+
+	t.Setenv("JWT_SECRET", "jwt-secret")
+	if !utils.CSRFSecretConfigured() {
+		t.Error("expected JWT_SECRET to serve as the CSRF key")
+	}
+}
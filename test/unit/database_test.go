@@ -0,0 +1,60 @@
+package unit
+
+import (
+	"fmt"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"go-journey/src/database"
+	"go-journey/src/database/migrations"
+	"go-journey/src/model"
+)
+
+var testDatabaseOnce sync.Once
+
+// useTestDatabase connects to the Postgres database named by TEST_DB_NAME,
+// with the other DB_* variables as for the server, and migrates it. Tests
+// that need a database are skipped when TEST_DB_NAME is not set.
+func useTestDatabase(t *testing.T) {
+	t.Helper()
+	name := os.Getenv("TEST_DB_NAME")
+	if name == "" {
+		t.Skip("TEST_DB_NAME is not set")
+	}
+	testDatabaseOnce.Do(func() {
+		os.Setenv("DB_NAME", name)
+		database.ConnectDB()
+		migrations.Migrate()
+	})
+}
+
+// uniqueName returns a name that no other test run has used
+func uniqueName(prefix string) string {
+	return fmt.Sprintf("%s-%d", prefix, time.Now().UnixNano())
+}
+
+// createTestUser stores an active user and deletes it again after the test
+func createTestUser(t *testing.T, user model.User) *model.User {
+	t.Helper()
+	if user.Username == "" {
+		user.Username = uniqueName("user")
+	}
+	if user.FullName == "" {
+		user.FullName = "Test User"
+	}
+	if user.Password == "" {
+		user.Password = "unusable"
+	}
+	if user.Role == "" {
+		user.Role = model.RoleUser
+	}
+	if err := database.DB.Create(&user).Error; err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		database.DB.Unscoped().Delete(&model.User{}, "id = ?", user.ID)
+	})
+	return &user
+}
@@ -0,0 +1,142 @@
+package unit
+
+import (
+	"testing"
+	"time"
+
+	"go-journey/src/database"
+	"go-journey/src/model"
+	"go-journey/src/utils"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+)
+
+// useMemoryDenylist gives the test an empty denylist and restores the previous one
+func useMemoryDenylist(t *testing.T) *utils.MemoryDenylist {
+	t.Helper()
+	previous := utils.Denylist
+	denylist := utils.NewMemoryDenylist()
+	utils.Denylist = denylist
+	t.Cleanup(func() { utils.Denylist = previous })
+	return denylist
+}
+
+func TestAccessTokenRevokedByDenylist(t *testing.T) {
+	useMemoryDenylist(t)
+
+	jti := uuid.New().String()
+	if err := utils.DenyToken(jti, time.Now().Add(time.Minute)); err != nil {
+		t.Fatal(err)
+	}
+	claims := jwt.MapClaims{"sub": "user-1", "jti": jti, "ver": float64(0), "type": "access"}
+	if !utils.AccessTokenRevoked(claims) {
+		t.Error("denylisted token is not revoked")
+	}
+
+	delete(claims, "jti")
+	if !utils.AccessTokenRevoked(claims) {
+		t.Error("token without jti is not revoked")
+	}
+}
+
+func TestMemoryDenylistExpiresAndPrunes(t *testing.T) {
+	denylist := useMemoryDenylist(t)
+
+	live, expiring := uuid.New().String(), uuid.New().String()
+	if err := utils.DenyToken(live, time.Now().Add(time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	if err := utils.DenyToken(expiring, time.Now().Add(20*time.Millisecond)); err != nil {
+		t.Fatal(err)
+	}
+	// Tokens that have already expired need no entry
+	if err := utils.DenyToken(uuid.New().String(), time.Now().Add(-time.Minute)); err != nil {
+		t.Fatal(err)
+	}
+	if denylist.Len() != 2 {
+		t.Fatalf("expected 2 entries, got %d", denylist.Len())
+	}
+
+	time.Sleep(30 * time.Millisecond)
+	if denied, _ := denylist.Contains(expiring); denied {
+		t.Error("expired entry still denies its token")
+	}
+	if err := denylist.Prune(); err != nil {
+		t.Fatal(err)
+	}
+	if denylist.Len() != 1 {
+		t.Errorf("expected 1 entry after pruning, got %d", denylist.Len())
+	}
+	if denied, _ := denylist.Contains(live); !denied {
+		t.Error("pruning removed a live entry")
+	}
+}
+
+func TestBumpedTokenVersionRevokesAccessTokens(t *testing.T) {
+	useTestDatabase(t)
+	useMemoryDenylist(t)
+
+	user := createTestUser(t, model.User{})
+	actor := createTestUser(t, model.User{Role: model.RoleAdmin})
+	claims := func(ver int) jwt.MapClaims {
+		return jwt.MapClaims{"sub": user.ID, "jti": uuid.New().String(), "ver": float64(ver), "type": "access"}
+	}
+
+	if utils.AccessTokenRevoked(claims(0)) {
+		t.Fatal("token with the current version is revoked")
+	}
+	if err := utils.BumpTokenVersion(user.ID); err != nil {
+		t.Fatal(err)
+	}
+	if !utils.AccessTokenRevoked(claims(0)) {
+		t.Error("token issued before the bump is not revoked")
+	}
+	if utils.AccessTokenRevoked(claims(1)) {
+		t.Error("token with the new version is revoked")
+	}
+
+	// Impersonation tokens also die with the actor's tokens
+	impersonation := claims(1)
+	impersonation["act"] = map[string]interface{}{"sub": actor.ID, "ver": float64(0)}
+	if utils.AccessTokenRevoked(impersonation) {
+		t.Fatal("impersonation token is revoked")
+	}
+	if err := utils.BumpTokenVersion(actor.ID); err != nil {
+		t.Fatal(err)
+	}
+	if !utils.AccessTokenRevoked(impersonation) {
+		t.Error("impersonation token survives a bump of the actor's version")
+	}
+}
+
+func TestPostgresDenylist(t *testing.T) {
+	useTestDatabase(t)
+
+	denylist := utils.NewPostgresDenylist()
+	live, expired := uuid.New().String(), uuid.New().String()
+	t.Cleanup(func() {
+		database.DB.Where("jti IN ?", []string{live, expired}).Delete(&model.DeniedToken{})
+	})
+	if err := denylist.Add(live, time.Now().Add(time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	if err := denylist.Add(expired, time.Now().Add(-time.Second)); err != nil {
+		t.Fatal(err)
+	}
+
+	if denied, err := denylist.Contains(live); err != nil || !denied {
+		t.Errorf("live entry not found (err %v)", err)
+	}
+	if denied, err := denylist.Contains(expired); err != nil || denied {
+		t.Errorf("expired entry still denies its token (err %v)", err)
+	}
+	if err := denylist.Prune(); err != nil {
+		t.Fatal(err)
+	}
+	var remaining int64
+	database.DB.Model(&model.DeniedToken{}).Where("jti IN ?", []string{live, expired}).Count(&remaining)
+	if remaining != 1 {
+		t.Errorf("expected only the live entry after pruning, got %d entries", remaining)
+	}
+}
@@ -0,0 +1,186 @@
+package unit
+
+import (
+	"crypto/ed25519"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"testing"
+
+	"go-journey/src/model"
+	"go-journey/src/utils"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+type jwk struct {
+	Kid string `json:"kid"`
+	Kty string `json:"kty"`
+	Alg string `json:"alg"`
+	Use string `json:"use"`
+	Crv string `json:"crv"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	X   string `json:"x"`
+}
+
+// publishedKeys returns the key set as another service would fetch it
+func publishedKeys(t *testing.T) map[string]jwk {
+	t.Helper()
+	body, err := json.Marshal(utils.JWKS())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var set struct {
+		Keys []jwk `json:"keys"`
+	}
+	if err := json.Unmarshal(body, &set); err != nil {
+		t.Fatal(err)
+	}
+	keys := make(map[string]jwk, len(set.Keys))
+	for _, k := range set.Keys {
+		keys[k.Kid] = k
+	}
+	return keys
+}
+
+func (k jwk) publicKey(t *testing.T) interface{} {
+	t.Helper()
+	decode := func(s string) []byte {
+		b, err := base64.RawURLEncoding.DecodeString(s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return b
+	}
+	switch k.Kty {
+	case "RSA":
+		return &rsa.PublicKey{N: new(big.Int).SetBytes(decode(k.N)), E: int(new(big.Int).SetBytes(decode(k.E)).Int64())}
+	case "OKP":
+		return ed25519.PublicKey(decode(k.X))
+	}
+	t.Fatalf("unexpected key type %q", k.Kty)
+	return nil
+}
+
+// verifyWithJWKS checks a token the way a relying party does, using only the published keys
+func verifyWithJWKS(t *testing.T, token string) error {
+	t.Helper()
+	keys := publishedKeys(t)
+	_, err := jwt.Parse(token, func(tok *jwt.Token) (interface{}, error) {
+		k := keys[tok.Header["kid"].(string)]
+		return k.publicKey(t), nil
+	}, jwt.WithValidMethods([]string{utils.JWTAlgorithm()}))
+	return err
+}
+
+func tokenKid(t *testing.T, token string) string {
+	t.Helper()
+	parsed, _, err := jwt.NewParser().ParseUnverified(token, jwt.MapClaims{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	kid, _ := parsed.Header["kid"].(string)
+	return kid
+}
+
+func TestKeyringSignsVerifiesAndRotates(t *testing.T) {
+	for _, tc := range []struct{ alg, kty string }{{"RS256", "RSA"}, {"EdDSA", "OKP"}} {
+		t.Run(tc.alg, func(t *testing.T) {
+			t.Setenv("JWT_ALG", tc.alg)
+			t.Cleanup(func() { _ = utils.UseSigningKeys(nil) })
+
+			first, err := utils.GenerateSigningKey(tc.alg)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := utils.UseSigningKeys([]model.SigningKey{first}); err != nil {
+				t.Fatal(err)
+			}
+
+			user := model.User{ID: "user-1", Role: model.RoleUser}
+			session := model.Session{ID: "session-1"}
+			before, err := utils.GenerateTokenPair(&user, &session)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if kid := tokenKid(t, before.AccessToken); kid != first.Kid {
+				t.Fatalf("token signed with kid %q, want the active key %q", kid, first.Kid)
+			}
+			if _, _, err := utils.ParseToken(before.AccessToken); err != nil {
+				t.Fatalf("token does not verify: %v", err)
+			}
+
+			published := publishedKeys(t)
+			if k := published[first.Kid]; k.Kty != tc.kty || k.Alg != tc.alg || k.Use != "sig" {
+				t.Errorf("unexpected JWK %+v", k)
+			}
+			if err := verifyWithJWKS(t, before.AccessToken); err != nil {
+				t.Errorf("token does not verify with the published key: %v", err)
+			}
+
+			// Rotation retires the first key but keeps it for verification
+			second, err := utils.GenerateSigningKey(tc.alg)
+			if err != nil {
+				t.Fatal(err)
+			}
+			first.Status = model.SigningKeyRetired
+			if err := utils.UseSigningKeys([]model.SigningKey{first, second}); err != nil {
+				t.Fatal(err)
+			}
+
+			after, err := utils.GenerateTokenPair(&user, &session)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if kid := tokenKid(t, after.AccessToken); kid != second.Kid {
+				t.Errorf("token signed with kid %q after rotation, want %q", kid, second.Kid)
+			}
+			for name, token := range map[string]string{"old": before.AccessToken, "new": after.AccessToken} {
+				if _, _, err := utils.ParseToken(token); err != nil {
+					t.Errorf("%s token does not verify after rotation: %v", name, err)
+				}
+				if err := verifyWithJWKS(t, token); err != nil {
+					t.Errorf("%s token does not verify with the published keys: %v", name, err)
+				}
+			}
+			if len(publishedKeys(t)) != 2 {
+				t.Errorf("expected both keys to be published, got %v", publishedKeys(t))
+			}
+
+			// Once the retired key is removed, its tokens no longer verify
+			if err := utils.UseSigningKeys([]model.SigningKey{second}); err != nil {
+				t.Fatal(err)
+			}
+			if _, _, err := utils.ParseToken(before.AccessToken); err == nil {
+				t.Error("token of a removed key still verifies")
+			}
+		})
+	}
+}
+
+func TestKeyringRejectsHS256Tokens(t *testing.T) {
+	t.Setenv("JWT_ALG", "RS256")
+	t.Setenv("JWT_SECRET", "test-secret")
+	t.Cleanup(func() { _ = utils.UseSigningKeys(nil) })
+
+	key, err := utils.GenerateSigningKey("RS256")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := utils.UseSigningKeys([]model.SigningKey{key}); err != nil {
+		t.Fatal(err)
+	}
+
+	// An attacker signs with the secret but names the real key
+	forged := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{"sub": "user-1", "type": "access"})
+	forged.Header["kid"] = key.Kid
+	signed, err := forged.SignedString([]byte("test-secret"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := utils.ParseToken(signed); err == nil {
+		t.Error("HS256 token accepted while RS256 is configured")
+	}
+}
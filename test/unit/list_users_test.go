@@ -0,0 +1,93 @@
+package unit
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"go-journey/src/model"
+	"go-journey/src/service"
+	"go-journey/src/validation"
+)
+
+func TestListUsersRequestValidation(t *testing.T) {
+	valid := validation.ListUsersRequest{
+		Page:           2,
+		PerPage:        100,
+		Status:         "pending",
+		RegisteredFrom: "2024-01-01",
+		RegisteredTo:   "2024-12-31",
+	}
+	if err := validation.ValidateStruct(&valid); err != nil {
+		t.Fatalf("expected a valid request, got %v", err)
+	}
+
+	cases := map[string]validation.ListUsersRequest{
+		"per_page too large": {PerPage: 101},
+		"unknown status":     {Status: "deleted"},
+		"bad date":           {RegisteredFrom: "01-02-2024"},
+	}
+	for name, req := range cases {
+		if err := validation.ValidateStruct(&req); err == nil {
+			t.Errorf("%s: expected a validation error", name)
+		}
+	}
+}
+
+func TestUserSortOrderWhitelist(t *testing.T) {
+	cases := map[string][2]string{
+		"":               {"register_date", "DESC"},
+		"username":       {"username", "ASC"},
+		"-full_name":     {"full_name", "DESC"},
+		"email":          {"email", "ASC"},
+		"-register_date": {"register_date", "DESC"},
+	}
+	for sort, want := range cases {
+		column, direction, err := service.UserSortOrder(sort)
+		if err != nil || column != want[0] || direction != want[1] {
+			t.Errorf("sort %q: got %s %s (%v), want %s %s", sort, column, direction, err, want[0], want[1])
+		}
+	}
+
+	for _, sort := range []string{"password", "id; DROP TABLE users", "--username", "failed_logins"} {
+		if _, _, err := service.UserSortOrder(sort); !errors.Is(err, service.ErrInvalidSort) {
+			t.Errorf("sort %q: expected ErrInvalidSort, got %v", sort, err)
+		}
+	}
+}
+
+func TestUserCursorRoundTrip(t *testing.T) {
+	registered := time.Date(2024, 5, 1, 8, 30, 0, 123456789, time.UTC)
+	last := model.User{ID: "0b6c2b1e-1111-2222-3333-444455556666", Username: "gani", RegisterDate: registered}
+
+	for _, sort := range []string{"", "username", "-register_date"} {
+		cursor, err := service.DecodeUserCursor(service.EncodeUserCursor(sort, &last), sort)
+		if err != nil {
+			t.Fatalf("sort %q: %v", sort, err)
+		}
+		if cursor.ID != last.ID {
+			t.Errorf("sort %q: cursor id %q, want %q", sort, cursor.ID, last.ID)
+		}
+	}
+
+	cursor, _ := service.DecodeUserCursor(service.EncodeUserCursor("-register_date", &last), "")
+	if cursor.Value != registered.Format(time.RFC3339Nano) {
+		t.Errorf("register_date cursor value %q lost precision", cursor.Value)
+	}
+}
+
+func TestUserCursorRejectsOtherSortsAndGarbage(t *testing.T) {
+	last := model.User{ID: "0b6c2b1e-1111-2222-3333-444455556666", Username: "gani"}
+	cursor := service.EncodeUserCursor("username", &last)
+
+	for _, sort := range []string{"-username", "full_name", ""} {
+		if _, err := service.DecodeUserCursor(cursor, sort); !errors.Is(err, service.ErrInvalidCursor) {
+			t.Errorf("cursor for username accepted with sort %q: %v", sort, err)
+		}
+	}
+	for _, garbage := range []string{"not-base64!", "e30", "eyJzIjoidXNlcm5hbWUifQ"} {
+		if _, err := service.DecodeUserCursor(garbage, "username"); !errors.Is(err, service.ErrInvalidCursor) {
+			t.Errorf("cursor %q: expected ErrInvalidCursor, got %v", garbage, err)
+		}
+	}
+}
@@ -0,0 +1,28 @@
+package unit
+
+import (
+	"testing"
+
+	"go-journey/src/mailer"
+)
+
+func TestMemoryMailerRecordsMessages(t *testing.T) {
+	m := mailer.NewMemoryMailer()
+
+	if err := m.Send(mailer.Message{To: "gani@example.com", Subject: "Reset your password", Body: "link"}); err != nil {
+		t.Fatalf("Send returned error: %v", err)
+	}
+
+	msgs := m.Messages()
+	if len(msgs) != 1 {
+		t.Fatalf("expected 1 message, got %d", len(msgs))
+	}
+	if msgs[0].To != "gani@example.com" || msgs[0].Subject != "Reset your password" {
+		t.Errorf("unexpected message: %+v", msgs[0])
+	}
+
+	m.Reset()
+	if len(m.Messages()) != 0 {
+		t.Errorf("expected no messages after Reset")
+	}
+}
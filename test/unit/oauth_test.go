@@ -0,0 +1,294 @@
+package unit
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+
+	"go-journey/src/database"
+	"go-journey/src/model"
+	"go-journey/src/oauth"
+	"go-journey/src/service"
+	"go-journey/src/utils"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+const fakeAccessToken = "provider-access-token"
+
+// fakeAuthServer is a local OAuth2 authorization server. Codes are issued
+// with authorize and only exchange with the PKCE verifier of their challenge.
+type fakeAuthServer struct {
+	*httptest.Server
+	mu         sync.Mutex
+	challenges map[string]string
+	userinfo   interface{}
+	emails     interface{}
+}
+
+func newFakeAuthServer(t *testing.T, userinfo interface{}, emails interface{}) *fakeAuthServer {
+	s := &fakeAuthServer{challenges: map[string]string{}, userinfo: userinfo, emails: emails}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseForm(); err != nil || r.Method != http.MethodPost {
+			http.Error(w, "bad request", http.StatusBadRequest)
+			return
+		}
+		s.mu.Lock()
+		challenge, ok := s.challenges[r.PostForm.Get("code")]
+		delete(s.challenges, r.PostForm.Get("code"))
+		s.mu.Unlock()
+
+		// Like GitHub, errors are reported with status 200
+		if !ok || r.PostForm.Get("grant_type") != "authorization_code" ||
+			r.PostForm.Get("client_id") != "client-id" || r.PostForm.Get("client_secret") != "client-secret" ||
+			oauth.CodeChallenge(r.PostForm.Get("code_verifier")) != challenge {
+			writeJSON(w, map[string]string{"error": "invalid_grant", "error_description": "bad code or verifier"})
+			return
+		}
+		writeJSON(w, map[string]string{"access_token": fakeAccessToken, "token_type": "bearer"})
+	})
+	serveWithToken := func(body func() interface{}) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			if r.Header.Get("Authorization") != "Bearer "+fakeAccessToken {
+				http.Error(w, "unauthorized", http.StatusUnauthorized)
+				return
+			}
+			writeJSON(w, body())
+		}
+	}
+	mux.HandleFunc("/userinfo", serveWithToken(func() interface{} {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		return s.userinfo
+	}))
+	mux.HandleFunc("/emails", serveWithToken(func() interface{} {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		return s.emails
+	}))
+
+	s.Server = httptest.NewServer(mux)
+	t.Cleanup(s.Close)
+	return s
+}
+
+// authorize approves a login as the provider's consent page would and
+// returns the code the browser brings back
+func (s *fakeAuthServer) authorize(challenge string) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	code := "code-" + challenge[:8]
+	s.challenges[code] = challenge
+	return code
+}
+
+func (s *fakeAuthServer) setUserinfo(userinfo interface{}) {
+	s.mu.Lock()
+	s.userinfo = userinfo
+	s.mu.Unlock()
+}
+
+// provider configures name to use the fake server through its OAUTH_* variables
+func (s *fakeAuthServer) provider(t *testing.T, name string) *oauth.Provider {
+	t.Helper()
+	prefix := "OAUTH_" + strings.ToUpper(name) + "_"
+	t.Setenv(prefix+"CLIENT_ID", "client-id")
+	t.Setenv(prefix+"CLIENT_SECRET", "client-secret")
+	t.Setenv(prefix+"REDIRECT_URI", "http://localhost/auth/oauth/"+name+"/callback")
+	t.Setenv(prefix+"AUTH_URL", s.URL+"/authorize")
+	t.Setenv(prefix+"TOKEN_URL", s.URL+"/token")
+	t.Setenv(prefix+"USERINFO_URL", s.URL+"/userinfo")
+	t.Setenv(prefix+"EMAILS_URL", s.URL+"/emails")
+
+	provider, ok := oauth.Get(name)
+	if !ok {
+		t.Fatalf("provider %s is not configured", name)
+	}
+	return provider
+}
+
+func writeJSON(w http.ResponseWriter, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(body)
+}
+
+func TestOAuthExchangeRequiresTheVerifier(t *testing.T) {
+	server := newFakeAuthServer(t, nil, nil)
+	provider := server.provider(t, "google")
+	ctx := context.Background()
+
+	verifier := "verifier-0123456789-0123456789-0123456789"
+	code := server.authorize(oauth.CodeChallenge(verifier))
+	if _, err := provider.Exchange(ctx, code, "another-verifier"); err == nil {
+		t.Fatal("expected the exchange to fail with the wrong verifier")
+	}
+
+	code = server.authorize(oauth.CodeChallenge(verifier))
+	token, err := provider.Exchange(ctx, code, verifier)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if token != fakeAccessToken {
+		t.Errorf("access token %q, want %q", token, fakeAccessToken)
+	}
+
+	if _, err := provider.Exchange(ctx, code, verifier); err == nil {
+		t.Error("expected a used code to be refused")
+	}
+}
+
+func TestOAuthFetchProfile(t *testing.T) {
+	ctx := context.Background()
+
+	google := newFakeAuthServer(t, map[string]interface{}{
+		"sub": "g-123", "email": "Gani@Example.com", "email_verified": true, "name": "Gani Ramdan",
+	}, nil)
+	profile, err := google.provider(t, "google").FetchProfile(ctx, fakeAccessToken)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := oauth.Profile{Subject: "g-123", Email: "Gani@Example.com", EmailVerified: true, Name: "Gani Ramdan", Username: "Gani"}
+	if profile != want {
+		t.Errorf("google profile %+v, want %+v", profile, want)
+	}
+	if _, err := google.provider(t, "google").FetchProfile(ctx, "wrong-token"); err == nil {
+		t.Error("expected an error for a rejected access token")
+	}
+
+	github := newFakeAuthServer(t, map[string]interface{}{"id": 42, "login": "gani"}, []map[string]interface{}{
+		{"email": "old@example.com", "primary": false, "verified": true},
+		{"email": "gani@example.com", "primary": true, "verified": false},
+	})
+	profile, err = github.provider(t, "github").FetchProfile(ctx, fakeAccessToken)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want = oauth.Profile{Subject: "42", Email: "gani@example.com", EmailVerified: false, Name: "gani", Username: "gani"}
+	if profile != want {
+		t.Errorf("github profile %+v, want %+v", profile, want)
+	}
+}
+
+// oauthLogin runs a whole social login against the fake server
+func oauthLogin(t *testing.T, server *fakeAuthServer, provider *oauth.Provider) (*model.User, error) {
+	t.Helper()
+	authURL, state, err := service.BeginOAuthLogin(provider)
+	if err != nil {
+		t.Fatal(err)
+	}
+	u, err := url.Parse(authURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if u.Query().Get("state") != state || u.Query().Get("code_challenge_method") != "S256" {
+		t.Fatalf("unexpected authorization URL %s", authURL)
+	}
+	code := server.authorize(u.Query().Get("code_challenge"))
+	return service.CompleteOAuthLogin(context.Background(), provider, code, state)
+}
+
+func TestOAuthLoginCreatesAndLinksUsers(t *testing.T) {
+	useTestDatabase(t)
+	t.Setenv("REGISTRATION_MODE", "open")
+
+	email := uniqueName("social") + "@example.com"
+	subject := uniqueName("subject")
+	server := newFakeAuthServer(t, map[string]interface{}{
+		"sub": subject, "email": email, "email_verified": true, "name": "Social User",
+	}, nil)
+	provider := server.provider(t, "google")
+
+	created, err := oauthLogin(t, server, provider)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		database.DB.Where("user_id = ?", created.ID).Delete(&model.UserIdentity{})
+		database.DB.Unscoped().Delete(&model.User{}, "id = ?", created.ID)
+	})
+	if created.Email != email || created.EmailVerifiedAt == nil || created.Role != model.RoleUser {
+		t.Errorf("unexpected new user %+v", created)
+	}
+
+	again, err := oauthLogin(t, server, provider)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again.ID != created.ID {
+		t.Errorf("second login created user %s, want the linked user %s", again.ID, created.ID)
+	}
+
+	// A new provider account with the same verified email is linked
+	server.setUserinfo(map[string]interface{}{
+		"sub": uniqueName("subject"), "email": email, "email_verified": true, "name": "Social User",
+	})
+	linked, err := oauthLogin(t, server, provider)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if linked.ID != created.ID {
+		t.Errorf("login with the same email created user %s, want %s", linked.ID, created.ID)
+	}
+	var identities int64
+	database.DB.Model(&model.UserIdentity{}).Where("user_id = ?", created.ID).Count(&identities)
+	if identities != 2 {
+		t.Errorf("expected 2 linked identities, got %d", identities)
+	}
+}
+
+func TestOAuthLoginRefusesUnverifiedLocalEmail(t *testing.T) {
+	useTestDatabase(t)
+	t.Setenv("REGISTRATION_MODE", "open")
+
+	local := createTestUser(t, model.User{Email: uniqueName("local") + "@example.com"})
+	server := newFakeAuthServer(t, map[string]interface{}{
+		"sub": uniqueName("subject"), "email": local.Email, "email_verified": true, "name": "Attacker",
+	}, nil)
+
+	_, err := oauthLogin(t, server, server.provider(t, "google"))
+	if !errors.Is(err, service.ErrOAuthEmailConflict) {
+		t.Fatalf("expected ErrOAuthEmailConflict, got %v", err)
+	}
+}
+
+func TestOAuthStateMustMatchBrowserCookie(t *testing.T) {
+	app := fiber.New()
+	app.Get("/auth/oauth/google/callback", func(c *fiber.Ctx) error {
+		if !utils.OAuthStateMatches(c, c.Query("state")) {
+			return c.SendStatus(fiber.StatusBadRequest)
+		}
+		return c.SendStatus(fiber.StatusOK)
+	})
+
+	cases := []struct {
+		name   string
+		cookie string
+		state  string
+		want   int
+	}{
+		{"same browser", "s1", "s1", fiber.StatusOK},
+		{"attacker state", "s1", "s2", fiber.StatusBadRequest},
+		{"no cookie", "", "s2", fiber.StatusBadRequest},
+	}
+	for _, tc := range cases {
+		req := httptest.NewRequest(fiber.MethodGet, "/auth/oauth/google/callback?state="+tc.state, nil)
+		if tc.cookie != "" {
+			req.Header.Set("Cookie", utils.OAuthStateCookie+"="+tc.cookie)
+		}
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.StatusCode != tc.want {
+			t.Errorf("%s: status %d, want %d", tc.name, resp.StatusCode, tc.want)
+		}
+	}
+}
@@ -0,0 +1,66 @@
+package unit
+
+import (
+	"strings"
+	"testing"
+
+	"go-journey/src/password"
+)
+
+func TestArgon2idHashRoundTrip(t *testing.T) {
+	params := password.HashParams{Algorithm: password.AlgorithmArgon2id, Memory: 1024, Time: 1, Threads: 1}
+
+	hash, err := params.Hash("Correct-Horse-9")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(hash, "$argon2id$v=19$m=1024,t=1,p=1$") {
+		t.Fatalf("unexpected encoding %q", hash)
+	}
+
+	if ok, rehash, err := params.Verify("Correct-Horse-9", hash); err != nil || !ok || rehash {
+		t.Errorf("Verify = %v, %v, %v; want true, false, nil", ok, rehash, err)
+	}
+	if ok, _, _ := params.Verify("wrong", hash); ok {
+		t.Errorf("wrong password accepted")
+	}
+
+	stronger := params
+	stronger.Time = 2
+	if _, rehash, _ := stronger.Verify("Correct-Horse-9", hash); !rehash {
+		t.Errorf("outdated parameters not flagged for rehash")
+	}
+}
+
+func TestBcryptHashNeedsRehashUnderArgon2id(t *testing.T) {
+	bcryptParams := password.HashParams{Algorithm: password.AlgorithmBcrypt, BcryptCost: 4}
+	hash, err := bcryptParams.Hash("Correct-Horse-9")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	argonParams := password.HashParams{Algorithm: password.AlgorithmArgon2id, Memory: 1024, Time: 1, Threads: 1}
+	ok, rehash, err := argonParams.Verify("Correct-Horse-9", hash)
+	if err != nil || !ok || !rehash {
+		t.Errorf("Verify = %v, %v, %v; want true, true, nil", ok, rehash, err)
+	}
+}
+
+func TestHashParamsFromEnvRejectsOutOfRangeValues(t *testing.T) {
+	t.Setenv("PASSWORD_HASH_ALG", "argon2id")
+	t.Setenv("PASSWORD_HASH_ARGON2_THREADS", "256")
+	t.Setenv("PASSWORD_HASH_ARGON2_TIME", "0")
+	t.Setenv("PASSWORD_HASH_ARGON2_MEMORY", "4")
+	t.Setenv("PASSWORD_HASH_BCRYPT_COST", "40")
+
+	params := password.HashParamsFromEnv()
+	if params.Threads != 2 || params.Time != 3 || params.Memory != 64*1024 || params.BcryptCost != 10 {
+		t.Fatalf("expected defaults for invalid values, got %+v", params)
+	}
+
+	t.Setenv("PASSWORD_HASH_ARGON2_THREADS", "4")
+	t.Setenv("PASSWORD_HASH_ARGON2_MEMORY", "31")
+	if params := password.HashParamsFromEnv(); params.Threads != 4 || params.Memory != 64*1024 {
+		t.Fatalf("expected memory below 8 KiB per thread to fall back, got %+v", params)
+	}
+}
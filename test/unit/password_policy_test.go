@@ -0,0 +1,53 @@
+package unit
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"go-journey/src/password"
+)
+
+func TestPasswordPolicyRejectsWeakPasswords(t *testing.T) {
+	policy := password.Policy{MinLength: 8, MaxLength: 72, MinClasses: 3}
+
+	cases := map[string]string{
+		"short":             "Ab1!",
+		"too few classes":   "alllowercase1",
+		"contains username": "Johnny#2024",
+		"contains email":    "Xx-jdoe-42",
+	}
+	for name, candidate := range cases {
+		err := policy.Check(candidate, "johnny", "jdoe@example.com")
+		var policyErr *password.PolicyError
+		if !errors.As(err, &policyErr) {
+			t.Errorf("%s: expected a policy error for %q, got %v", name, candidate, err)
+		}
+	}
+
+	if err := policy.Check("Correct-Horse-9", "johnny", "jdoe@example.com"); err != nil {
+		t.Errorf("strong password rejected: %v", err)
+	}
+}
+
+func TestPasswordPolicyChecksBreachedList(t *testing.T) {
+	dir := t.TempDir()
+	breached := "Tr0ub4dor&3"
+	sum := sha1.Sum([]byte(breached))
+	hash := strings.ToUpper(hex.EncodeToString(sum[:]))
+	if err := os.WriteFile(filepath.Join(dir, hash[:5]+".txt"), []byte(hash[5:]+":42\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	policy := password.Policy{MinLength: 8, MinClasses: 3, BreachedDir: dir}
+	if err := policy.Check(breached); err == nil {
+		t.Errorf("breached password accepted")
+	}
+	if err := policy.Check("Tr0ub4dor&4"); err != nil {
+		t.Errorf("unlisted password rejected: %v", err)
+	}
+}
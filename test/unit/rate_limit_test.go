@@ -0,0 +1,87 @@
+package unit
+
+import (
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"go-journey/src/middleware"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/middleware/limiter"
+)
+
+// counterStorage counts in memory like database.Storage does in Postgres
+type counterStorage struct {
+	mu         sync.Mutex
+	hits       map[string]int
+	increments int
+}
+
+func (s *counterStorage) Increment(key string, exp time.Duration) (int, time.Time, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.hits[key]++
+	s.increments++
+	return s.hits[key], time.Now().Add(exp), nil
+}
+
+func (s *counterStorage) Get(key string) ([]byte, error)                      { return nil, nil }
+func (s *counterStorage) Set(key string, val []byte, exp time.Duration) error { return nil }
+func (s *counterStorage) Delete(key string) error                             { return nil }
+func (s *counterStorage) Reset() error                                        { return nil }
+func (s *counterStorage) Close() error                                        { return nil }
+
+func TestAtomicFixedWindowCountsInStorage(t *testing.T) {
+	storage := &counterStorage{hits: map[string]int{}}
+	app := fiber.New()
+	app.Get("/", limiter.New(limiter.Config{
+		Max:               2,
+		Expiration:        time.Minute,
+		Storage:           storage,
+		LimiterMiddleware: middleware.AtomicFixedWindow{},
+	}), func(c *fiber.Ctx) error {
+		return c.SendStatus(fiber.StatusOK)
+	})
+
+	for i, want := range []int{fiber.StatusOK, fiber.StatusOK, fiber.StatusTooManyRequests} {
+		resp, err := app.Test(httptest.NewRequest(fiber.MethodGet, "/", nil))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.StatusCode != want {
+			t.Fatalf("request %d: status %d, want %d", i+1, resp.StatusCode, want)
+		}
+		if want == fiber.StatusTooManyRequests && resp.Header.Get(fiber.HeaderRetryAfter) == "" {
+			t.Error("limited response has no Retry-After header")
+		}
+		if want == fiber.StatusOK && resp.Header.Get("X-RateLimit-Limit") != "2" {
+			t.Errorf("request %d: X-RateLimit-Limit %q", i+1, resp.Header.Get("X-RateLimit-Limit"))
+		}
+	}
+	if storage.increments != 3 {
+		t.Errorf("expected 3 increments in the storage, got %d", storage.increments)
+	}
+}
+
+func TestAtomicFixedWindowFallsBackWithoutIncrement(t *testing.T) {
+	app := fiber.New()
+	app.Get("/", limiter.New(limiter.Config{
+		Max:               1,
+		Expiration:        time.Minute,
+		LimiterMiddleware: middleware.AtomicFixedWindow{},
+	}), func(c *fiber.Ctx) error {
+		return c.SendStatus(fiber.StatusOK)
+	})
+
+	for i, want := range []int{fiber.StatusOK, fiber.StatusTooManyRequests} {
+		resp, err := app.Test(httptest.NewRequest(fiber.MethodGet, "/", nil))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.StatusCode != want {
+			t.Fatalf("request %d: status %d, want %d", i+1, resp.StatusCode, want)
+		}
+	}
+}
@@ -0,0 +1,43 @@
+package unit
+
+import (
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"go-journey/src/middleware"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func TestRequireRecentAuth(t *testing.T) {
+	cases := []struct {
+		name     string
+		authTime interface{}
+		want     int
+	}{
+		{"fresh login", time.Now().Add(-time.Minute), fiber.StatusOK},
+		{"old login", time.Now().Add(-time.Hour), fiber.StatusForbidden},
+		{"no auth_time", nil, fiber.StatusForbidden},
+	}
+
+	for _, tc := range cases {
+		app := fiber.New()
+		app.Get("/", func(c *fiber.Ctx) error {
+			if tc.authTime != nil {
+				c.Locals("authTime", tc.authTime)
+			}
+			return c.Next()
+		}, middleware.RequireRecentAuth(5*time.Minute), func(c *fiber.Ctx) error {
+			return c.SendStatus(fiber.StatusOK)
+		})
+
+		resp, err := app.Test(httptest.NewRequest(fiber.MethodGet, "/", nil))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.StatusCode != tc.want {
+			t.Errorf("%s: status %d, want %d", tc.name, resp.StatusCode, tc.want)
+		}
+	}
+}
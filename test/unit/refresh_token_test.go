@@ -0,0 +1,108 @@
+package unit
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"go-journey/src/controller"
+	"go-journey/src/database"
+	"go-journey/src/model"
+	"go-journey/src/service"
+	"go-journey/src/utils"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type refreshResult struct {
+	status       int
+	code         string
+	accessToken  string
+	refreshToken string
+}
+
+func postRefresh(t *testing.T, app *fiber.App, refreshToken string) refreshResult {
+	t.Helper()
+	req := httptest.NewRequest(fiber.MethodPost, "/auth/refresh", strings.NewReader(`{"refreshToken":"`+refreshToken+`"}`))
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := app.Test(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var body struct {
+		Code string `json:"code"`
+		Data struct {
+			AccessToken  string `json:"accessToken"`
+			RefreshToken string `json:"refreshToken"`
+		} `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		t.Fatal(err)
+	}
+	return refreshResult{resp.StatusCode, body.Code, body.Data.AccessToken, body.Data.RefreshToken}
+}
+
+func TestRefreshTokenReuseRevokesTheFamily(t *testing.T) {
+	useTestDatabase(t)
+	t.Setenv("JWT_SECRET", "test-secret")
+	t.Setenv("JWT_ALG", "")
+
+	user := createTestUser(t, model.User{})
+	t.Cleanup(func() {
+		database.DB.Where("user_id = ?", user.ID).Delete(&model.RefreshToken{})
+		database.DB.Where("user_id = ?", user.ID).Delete(&model.Session{})
+		database.DB.Where("user_id = ?", user.ID).Delete(&model.SecurityEvent{})
+	})
+
+	tokens, session, err := service.StartSession(user, service.SessionMeta{DeviceName: "test"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	app := fiber.New()
+	app.Post("/auth/refresh", controller.Refresh)
+
+	rotated := postRefresh(t, app, tokens.RefreshToken)
+	if rotated.status != fiber.StatusOK || rotated.refreshToken == "" || rotated.refreshToken == tokens.RefreshToken {
+		t.Fatalf("first refresh: %+v", rotated)
+	}
+
+	// An attacker replays the stolen, already rotated token
+	reused := postRefresh(t, app, tokens.RefreshToken)
+	if reused.status != fiber.StatusUnauthorized || reused.code != "refresh_token_reused" {
+		t.Fatalf("replayed refresh token: %+v", reused)
+	}
+
+	revoked, err := service.GetSession(session.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if revoked.RevokedAt == nil {
+		t.Error("session was not revoked")
+	}
+
+	// The legitimate client's newer token belongs to the same family
+	if after := postRefresh(t, app, rotated.refreshToken); after.status != fiber.StatusUnauthorized {
+		t.Errorf("refresh with the rotated token after reuse: %+v", after)
+	}
+
+	// So does its access token
+	_, claims, err := utils.ParseToken(rotated.accessToken)
+	if err != nil {
+		t.Fatal(err)
+	}
+	jti, _ := claims["jti"].(string)
+	if denied, err := utils.Denylist.Contains(jti); err != nil || !denied {
+		t.Errorf("access token of the revoked session is not denylisted (err %v)", err)
+	}
+
+	var events int64
+	database.DB.Model(&model.SecurityEvent{}).
+		Where("user_id = ? AND type = ?", user.ID, model.SecurityEventRefreshTokenReuse).
+		Count(&events)
+	if events != 1 {
+		t.Errorf("expected 1 refresh_token_reuse event, got %d", events)
+	}
+}
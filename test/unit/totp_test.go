@@ -0,0 +1,43 @@
+package unit
+
+import (
+	"encoding/base32"
+	"testing"
+	"time"
+
+	"go-journey/src/utils"
+)
+
+// rfc6238Secret is the SHA-1 test key from RFC 6238 appendix B
+var rfc6238Secret = base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString([]byte("12345678901234567890"))
+
+func TestTOTPCodeMatchesRFC6238Vectors(t *testing.T) {
+	cases := map[int64]string{
+		59:         "287082",
+		1111111109: "081804",
+		1234567890: "005924",
+		2000000000: "279037",
+	}
+	for unix, want := range cases {
+		got, err := utils.TOTPCode(rfc6238Secret, utils.TOTPStep(time.Unix(unix, 0)))
+		if err != nil {
+			t.Fatalf("TOTPCode(%d): %v", unix, err)
+		}
+		if got != want {
+			t.Errorf("TOTPCode(%d) = %s, want %s", unix, got, want)
+		}
+	}
+}
+
+func TestValidateTOTPAcceptsAdjacentStepOnly(t *testing.T) {
+	now := time.Unix(1234567890, 0)
+	previous, _ := utils.TOTPCode(rfc6238Secret, utils.TOTPStep(now)-1)
+	stale, _ := utils.TOTPCode(rfc6238Secret, utils.TOTPStep(now)-3)
+
+	if step, ok := utils.ValidateTOTP(rfc6238Secret, previous, now); !ok || step != utils.TOTPStep(now)-1 {
+		t.Errorf("previous step code rejected")
+	}
+	if _, ok := utils.ValidateTOTP(rfc6238Secret, stale, now); ok {
+		t.Errorf("stale code accepted")
+	}
+}
@@ -0,0 +1,29 @@
+package unit
+
+import (
+	"math"
+	"testing"
+
+	"go-journey/src/utils"
+)
+
+func TestTrigramSimilarityToleratesTypos(t *testing.T) {
+	// Same trigram sets as pg_trgm: 10 shared out of 14 distinct
+	if got := utils.TrigramSimilarity("ganii ramdan", "Gani Ramdani"); math.Abs(got-10.0/14.0) > 1e-9 {
+		t.Fatalf("expected similarity 10/14, got %v", got)
+	}
+	if got := utils.TrigramSimilarity("Gani", "gani"); got != 1 {
+		t.Fatalf("expected identical words to score 1, got %v", got)
+	}
+	if got := utils.TrigramSimilarity("ganii", "budi"); got >= 0.3 {
+		t.Fatalf("expected unrelated words to score below the threshold, got %v", got)
+	}
+}
+
+func TestHighlightMatchesMarksSimilarWordsAndEscapes(t *testing.T) {
+	got := utils.HighlightMatches("Gani Ramdani <admin>", "ganii ramdan", 0.3)
+	want := "<mark>Gani</mark> <mark>Ramdani</mark> &lt;admin&gt;"
+	if got != want {
+		t.Fatalf("expected %q, got %q", want, got)
+	}
+}
@@ -0,0 +1,59 @@
+package unit
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"go-journey/src/model"
+	"go-journey/src/res"
+)
+
+func TestUserLockoutSummarisesThrottles(t *testing.T) {
+	now := time.Now()
+	expired := now.Add(-time.Minute)
+	locked := now.Add(10 * time.Minute)
+	throttles := []model.AccountThrottle{
+		{UserID: "user-1", IP: "10.0.0.1", FailedCount: 2, LastFailedAt: now.Add(-time.Hour), LockedUntil: &expired},
+		{UserID: "user-1", IP: "10.0.0.2", FailedCount: 5, LastFailedAt: now.Add(-time.Second), LockedUntil: &locked},
+	}
+
+	lockout := res.NewUserLockout(throttles, now)
+	if lockout.FailedLogins != 7 {
+		t.Errorf("expected 7 failed logins, got %d", lockout.FailedLogins)
+	}
+	if lockout.LastFailedAt == nil || !lockout.LastFailedAt.Equal(now.Add(-time.Second)) {
+		t.Errorf("expected the latest failure, got %v", lockout.LastFailedAt)
+	}
+	if lockout.LockedUntil == nil || !lockout.LockedUntil.Equal(locked) {
+		t.Errorf("expected locked until %v, got %v", locked, lockout.LockedUntil)
+	}
+
+	if l := res.NewUserLockout(throttles[:1], now); l.LockedUntil != nil {
+		t.Errorf("expired lockout reported as locked until %v", l.LockedUntil)
+	}
+	if l := res.NewUserLockout(nil, now); l.FailedLogins != 0 || l.LockedUntil != nil || l.LastFailedAt != nil {
+		t.Errorf("expected an empty lockout, got %+v", l)
+	}
+}
+
+func TestUserResponseLockoutIsOptional(t *testing.T) {
+	response := res.NewUserResponse(&model.User{ID: "user-1"})
+	body, err := json.Marshal(response)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(body), "lockout") {
+		t.Errorf("lockout included without being requested: %s", body)
+	}
+
+	response.Lockout = res.NewUserLockout(nil, time.Now())
+	body, err = json.Marshal(response)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(body), `"lockout":{"failed_logins":0,"last_failed_at":null,"locked_until":null}`) {
+		t.Errorf("unexpected lockout encoding: %s", body)
+	}
+}
@@ -0,0 +1,32 @@
+package unit
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"go-journey/src/model"
+	"go-journey/src/res"
+)
+
+func TestUserResponseOmitsSecurityState(t *testing.T) {
+	enabled := time.Now()
+	user := model.User{
+		ID:            "user-1",
+		Username:      "gani",
+		Password:      "$argon2id$hash",
+		TOTPSecret:    "JBSWY3DPEHPK3PXP",
+		TOTPEnabledAt: &enabled,
+	}
+
+	body, err := json.Marshal(res.NewUserResponse(&user))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, field := range []string{"password", "totp", "argon2id", "JBSWY3DPEHPK3PXP", "magic_link"} {
+		if strings.Contains(string(body), field) {
+			t.Errorf("user response exposes %q: %s", field, body)
+		}
+	}
+}